@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pritunl/pritunl-client-electron/cli/errortypes"
+	"github.com/pritunl/pritunl-client-electron/cli/history"
+	"github.com/pritunl/pritunl-client-electron/cli/sprofile"
+	"github.com/spf13/cobra"
+)
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history [profile_id]",
+	Short: "Show connection history",
+	Run: func(cmd *cobra.Command, args []string) {
+		prflId := ""
+		if len(args) > 0 {
+			sprfl, err := sprofile.Match(args[0])
+			cobra.CheckErr(err)
+			prflId = sprfl.Id
+		}
+
+		since, err := history.ParseTime(historySince)
+		cobra.CheckErr(err)
+
+		until, err := history.ParseTime(historyUntil)
+		cobra.CheckErr(err)
+
+		records, err := history.Get(prflId, since, until)
+		cobra.CheckErr(err)
+
+		if jsonFormat || jsonFormated {
+			var output []byte
+			if jsonFormated {
+				output, err = json.MarshalIndent(records, "", "  ")
+			} else {
+				output, err = json.Marshal(records)
+			}
+			if err != nil {
+				err = &errortypes.ParseError{
+					errors.Wrap(err, "cmd: Failed to marshal history"),
+				}
+				cobra.CheckErr(err)
+			}
+
+			fmt.Println(string(output))
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{
+			"Time",
+			"Profile ID",
+			"Mode",
+			"State",
+			"Remote",
+			"Reason",
+			"Duration",
+		})
+		table.SetBorder(true)
+
+		for _, record := range records {
+			remote := record.Remote
+			if remote == "" {
+				remote = "-"
+			}
+			reason := record.Reason
+			if reason == "" {
+				reason = "-"
+			}
+
+			table.Append([]string{
+				record.Timestamp.Local().Format("2006-01-02 15:04:05"),
+				record.ProfileId,
+				record.Mode,
+				record.State,
+				remote,
+				reason,
+				record.FormatedDuration(),
+			})
+		}
+
+		table.Render()
+	},
+}
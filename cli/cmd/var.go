@@ -6,6 +6,8 @@ var (
 	passwordPrompt bool
 	jsonFormat     bool
 	jsonFormated   bool
+	historySince   string
+	historyUntil   string
 )
 
 func init() {
@@ -46,4 +48,33 @@ func init() {
 		false,
 		"Format output in indented JSON",
 	)
+
+	HistoryCmd.Flags().StringVarP(
+		&historySince,
+		"since",
+		"s",
+		"",
+		"Show records after time (RFC3339 or duration such as 24h)",
+	)
+	HistoryCmd.Flags().StringVarP(
+		&historyUntil,
+		"until",
+		"u",
+		"",
+		"Show records before time (RFC3339 or duration such as 1h)",
+	)
+	HistoryCmd.Flags().BoolVarP(
+		&jsonFormat,
+		"json",
+		"j",
+		false,
+		"Format output in JSON",
+	)
+	HistoryCmd.Flags().BoolVarP(
+		&jsonFormated,
+		"json-formatted",
+		"f",
+		false,
+		"Format output in indented JSON",
+	)
 }
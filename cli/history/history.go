@@ -0,0 +1,123 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/cli/errortypes"
+	"github.com/pritunl/pritunl-client-electron/cli/service"
+)
+
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	ProfileId string    `json:"profile_id"`
+	Mode      string    `json:"mode"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Remote    string    `json:"remote"`
+	Reason    string    `json:"reason"`
+	Duration  int64     `json:"duration"`
+}
+
+func (r *Record) FormatedDuration() string {
+	if r.Duration <= 0 {
+		return "-"
+	}
+	return (time.Duration(r.Duration) * time.Second).String()
+}
+
+// ParseTime parses a RFC3339 timestamp or a duration relative to now
+func ParseTime(val string) (tm time.Time, err error) {
+	if val == "" {
+		return
+	}
+
+	dur, e := time.ParseDuration(val)
+	if e == nil {
+		tm = time.Now().Add(-dur)
+		return
+	}
+
+	tm, err = time.Parse(time.RFC3339, val)
+	if err != nil {
+		err = errortypes.ParseError{
+			errors.Wrap(err, "history: Invalid time, use RFC3339 or "+
+				"duration such as 24h"),
+		}
+		return
+	}
+
+	return
+}
+
+func Get(prflId string, since, until time.Time) (
+	records []*Record, err error) {
+
+	query := url.Values{}
+	if prflId != "" {
+		query.Set("profile_id", prflId)
+	}
+	if !since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d", since.Unix()))
+	}
+	if !until.IsZero() {
+		query.Set("until", fmt.Sprintf("%d", until.Unix()))
+	}
+
+	reqUrl := service.GetAddress() + "/history"
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	authKey, err := service.GetAuthKey()
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		err = errortypes.RequestError{
+			errors.Wrap(err, "history: Get request failed"),
+		}
+		return
+	}
+
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		req.Host = "unix"
+	}
+	req.Header.Set("Auth-Key", authKey)
+	req.Header.Set("User-Agent", "pritunl")
+
+	resp, err := service.GetClient().Do(req)
+	if err != nil {
+		err = errortypes.RequestError{
+			errors.Wrap(err, "history: Request failed"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = errortypes.RequestError{
+			errors.Newf("history: Request failed with status %d",
+				resp.StatusCode),
+		}
+		return
+	}
+
+	records = []*Record{}
+	err = json.NewDecoder(resp.Body).Decode(&records)
+	if err != nil {
+		err = errortypes.ParseError{
+			errors.Wrap(err, "history: Failed to parse response"),
+		}
+		return
+	}
+
+	return
+}
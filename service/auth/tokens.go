@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	ReadScope           = "read"
+	ProfileControlScope = "profile-control"
+	ConfigScope         = "config"
+	AdminScope          = "admin"
+)
+
+var (
+	tokens     = map[string]*Token{}
+	tokensLock = sync.RWMutex{}
+)
+
+type Token struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Hash      string    `json:"hash,omitempty"`
+	Secret    string    `json:"secret,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (t *Token) HasScope(scope string) bool {
+	for _, tokenScope := range t.Scopes {
+		if tokenScope == scope || tokenScope == AdminScope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Token) copy() *Token {
+	scopes := make([]string, len(t.Scopes))
+	copy(scopes, t.Scopes)
+
+	return &Token{
+		Id:        t.Id,
+		Name:      t.Name,
+		Scopes:    scopes,
+		Timestamp: t.Timestamp,
+	}
+}
+
+func ValidScope(scope string) bool {
+	switch scope {
+	case ReadScope, ProfileControlScope, ConfigScope, AdminScope:
+		return true
+	}
+
+	return false
+}
+
+func GetTokensPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-tokens.json")
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func saveTokens() (err error) {
+	pth := GetTokensPath()
+
+	tokensList := []*Token{}
+	for _, tokn := range tokens {
+		tokensList = append(tokensList, tokn)
+	}
+
+	data, err := json.MarshalIndent(tokensList, "", "\t")
+	if err != nil {
+		err = &WriteError{
+			errors.Wrap(err, "auth: Failed to marshal tokens"),
+		}
+		return
+	}
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	err = utils.CreateWrite(pth, string(data), 0600)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func LoadTokens() (err error) {
+	pth := GetTokensPath()
+
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+
+		err = &ReadError{
+			errors.Wrap(err, "auth: Failed to read tokens"),
+		}
+		return
+	}
+
+	tokensList := []*Token{}
+	err = json.Unmarshal(data, &tokensList)
+	if err != nil {
+		err = &ParseError{
+			errors.Wrap(err, "auth: Failed to parse tokens"),
+		}
+		return
+	}
+
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+
+	tokens = map[string]*Token{}
+	for _, tokn := range tokensList {
+		if tokn.Id == "" || tokn.Hash == "" {
+			continue
+		}
+		tokens[tokn.Id] = tokn
+	}
+
+	return
+}
+
+// CreateToken creates a scoped token, the returned token is the only copy
+// that includes the secret
+func CreateToken(name string, scopes []string) (tokn *Token, err error) {
+	if len(scopes) == 0 {
+		err = &ParseError{
+			errors.New("auth: Token requires at least one scope"),
+		}
+		return
+	}
+
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			err = &ParseError{
+				errors.Newf("auth: Unknown token scope '%s'", scope),
+			}
+			return
+		}
+	}
+
+	tokenId, err := utils.RandStr(16)
+	if err != nil {
+		return
+	}
+
+	secret, err := utils.RandStr(48)
+	if err != nil {
+		return
+	}
+	secret = tokenId + "." + secret
+
+	stored := &Token{
+		Id:        tokenId,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(secret),
+		Timestamp: time.Now(),
+	}
+
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+
+	tokens[tokenId] = stored
+
+	err = saveTokens()
+	if err != nil {
+		delete(tokens, tokenId)
+		return
+	}
+
+	tokn = stored.copy()
+	tokn.Secret = secret
+
+	return
+}
+
+func GetTokens() (tokensList []*Token) {
+	tokensLock.RLock()
+	defer tokensLock.RUnlock()
+
+	tokensList = []*Token{}
+	for _, tokn := range tokens {
+		tokensList = append(tokensList, tokn.copy())
+	}
+
+	sort.Slice(tokensList, func(i, j int) bool {
+		return tokensList[i].Timestamp.Before(tokensList[j].Timestamp)
+	})
+
+	return
+}
+
+func RevokeToken(tokenId string) (found bool, err error) {
+	tokensLock.Lock()
+	defer tokensLock.Unlock()
+
+	tokn := tokens[tokenId]
+	if tokn == nil {
+		return
+	}
+	found = true
+
+	delete(tokens, tokenId)
+
+	err = saveTokens()
+	if err != nil {
+		tokens[tokenId] = tokn
+		return
+	}
+
+	return
+}
+
+// ValidateToken returns the scoped token matching the secret or nil
+func ValidateToken(secret string) *Token {
+	tokenId := strings.SplitN(secret, ".", 2)[0]
+	if tokenId == "" || tokenId == secret {
+		return nil
+	}
+
+	tokensLock.RLock()
+	tokn := tokens[tokenId]
+	tokensLock.RUnlock()
+
+	if tokn == nil {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare(
+		[]byte(hashSecret(secret)), []byte(tokn.Hash)) != 1 {
+
+		return nil
+	}
+
+	return tokn.copy()
+}
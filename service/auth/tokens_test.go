@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestHashSecret(t *testing.T) {
+	tests := []struct {
+		secret   string
+		expected string
+	}{
+		{
+			secret: "",
+			expected: "e3b0c44298fc1c149afbf4c8996fb924" +
+				"27ae41e4649b934ca495991b7852b855",
+		},
+		{
+			secret: "abc",
+			expected: "ba7816bf8f01cfea414140de5dae2223" +
+				"b00361a396177a9cb410ff61f20015ad",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.secret, func(t *testing.T) {
+			hash := hashSecret(test.secret)
+			if hash != test.expected {
+				t.Errorf("hash %s, expected %s", hash, test.expected)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		scope    string
+		expected bool
+	}{
+		{
+			name:     "none",
+			scopes:   []string{},
+			scope:    ReadScope,
+			expected: false,
+		},
+		{
+			name:     "match",
+			scopes:   []string{ReadScope},
+			scope:    ReadScope,
+			expected: true,
+		},
+		{
+			name:     "other",
+			scopes:   []string{ReadScope},
+			scope:    ConfigScope,
+			expected: false,
+		},
+		{
+			name:     "multiple",
+			scopes:   []string{ReadScope, ProfileControlScope},
+			scope:    ProfileControlScope,
+			expected: true,
+		},
+		{
+			name:     "admin",
+			scopes:   []string{AdminScope},
+			scope:    ConfigScope,
+			expected: true,
+		},
+		{
+			name:     "admin_required",
+			scopes:   []string{ReadScope, ProfileControlScope, ConfigScope},
+			scope:    AdminScope,
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokn := &Token{
+				Scopes: test.scopes,
+			}
+			if tokn.HasScope(test.scope) != test.expected {
+				t.Errorf("scope %s, expected %t", test.scope, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidScope(t *testing.T) {
+	tests := []struct {
+		scope    string
+		expected bool
+	}{
+		{
+			scope:    ReadScope,
+			expected: true,
+		},
+		{
+			scope:    ProfileControlScope,
+			expected: true,
+		},
+		{
+			scope:    ConfigScope,
+			expected: true,
+		},
+		{
+			scope:    AdminScope,
+			expected: true,
+		},
+		{
+			scope:    "",
+			expected: false,
+		},
+		{
+			scope:    "Admin",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scope, func(t *testing.T) {
+			if ValidScope(test.scope) != test.expected {
+				t.Errorf("valid %s, expected %t", test.scope, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	secret := "tokenid.secret"
+
+	tokensLock.Lock()
+	tokens = map[string]*Token{
+		"tokenid": {
+			Id:     "tokenid",
+			Name:   "test",
+			Scopes: []string{ReadScope},
+			Hash:   hashSecret(secret),
+		},
+	}
+	tokensLock.Unlock()
+
+	tests := []struct {
+		name     string
+		secret   string
+		expected bool
+	}{
+		{
+			name:     "valid",
+			secret:   secret,
+			expected: true,
+		},
+		{
+			name:     "wrong_secret",
+			secret:   "tokenid.other",
+			expected: false,
+		},
+		{
+			name:     "unknown_id",
+			secret:   "otherid.secret",
+			expected: false,
+		},
+		{
+			name:     "no_id",
+			secret:   "tokenid",
+			expected: false,
+		},
+		{
+			name:     "empty_id",
+			secret:   ".secret",
+			expected: false,
+		},
+		{
+			name:     "empty",
+			secret:   "",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokn := ValidateToken(test.secret)
+			if (tokn != nil) != test.expected {
+				t.Fatalf("valid %t, expected %t", tokn != nil, test.expected)
+			}
+			if tokn == nil {
+				return
+			}
+
+			if tokn.Hash != "" || tokn.Secret != "" {
+				t.Errorf("token hash or secret not cleared")
+			}
+			if !tokn.HasScope(ReadScope) || tokn.HasScope(ConfigScope) {
+				t.Errorf("token scopes %v", tokn.Scopes)
+			}
+		})
+	}
+}
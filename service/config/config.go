@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"sync"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/types"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -15,19 +17,69 @@ var (
 	Config            = &ConfigData{}
 	StaticRoot        = ""
 	StaticTestingRoot = ""
+	lock              = sync.RWMutex{}
 )
 
+type AccessRule struct {
+	Uids   []int    `json:"uids"`
+	Groups []string `json:"groups"`
+}
+
+// AccessPolicy restricts local socket callers by peer credentials. When
+// the policy is set any permission without a rule is limited to root.
+// Admin covers the service level routes such as stop, restart and the
+// profile token routes used by the client.
+type AccessPolicy struct {
+	Read    *AccessRule `json:"read"`
+	Control *AccessRule `json:"control"`
+	Config  *AccessRule `json:"config"`
+	Network *AccessRule `json:"network"`
+	Admin   *AccessRule `json:"admin"`
+}
+
+// ResolverConfig sets the resolvers used for remote hostname lookups,
+// nameservers use plain DNS, DoT servers use host:port with port 853 by
+// default and DoH servers use https URLs. DoH servers with a hostname are
+// resolved with the DoT servers and nameservers. Timeout is in seconds.
+type ResolverConfig struct {
+	Nameservers    []string `json:"nameservers"`
+	DotServers     []string `json:"dot_servers"`
+	DohServers     []string `json:"doh_servers"`
+	Timeout        int      `json:"timeout"`
+	SystemFallback bool     `json:"system_fallback"`
+}
+
+// DnsStubConfig enables a local DNS resolver on the loopback address that
+// sends queries for the search domains of each connection to the
+// connection DNS servers and all other queries to the system resolvers.
+// The stub is only supported on Linux. CacheTtl is in seconds.
+type DnsStubConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Address  string `json:"address"`
+	CacheTtl int    `json:"cache_ttl"`
+}
+
 type ConfigData struct {
-	path              string `json:"-"`
-	loaded            bool   `json:"-"`
-	DisableDnsWatch   bool   `json:"disable_dns_watch"`
-	EnableDnsRefresh  bool   `json:"enable_dns_refresh"`
-	DisableWakeWatch  bool   `json:"disable_wake_watch"`
-	DisableNetClean   bool   `json:"disable_net_clean"`
-	DisableWgDns      bool   `json:"disable_wg_dns"`
-	ForceLocalTpm     bool   `json:"force_local_tpm"`
-	InterfaceMetric   int    `json:"interface_metric"`
-	EnclavePrivateKey string `json:"enclave_private_key"`
+	path              string                 `json:"-"`
+	loaded            bool                   `json:"-"`
+	DisableDnsWatch   bool                   `json:"disable_dns_watch"`
+	EnableDnsRefresh  bool                   `json:"enable_dns_refresh"`
+	DisableWakeWatch  bool                   `json:"disable_wake_watch"`
+	DisableNetClean   bool                   `json:"disable_net_clean"`
+	DisableWgDns      bool                   `json:"disable_wg_dns"`
+	ForceLocalTpm     bool                   `json:"force_local_tpm"`
+	InterfaceMetric   int                    `json:"interface_metric"`
+	HooksTimeout      int                    `json:"hooks_timeout"`
+	RemoteRace        bool                   `json:"remote_race"`
+	RemoteRaceDelay   int                    `json:"remote_race_delay"`
+	LatencySortTtl    int                    `json:"latency_sort_ttl"`
+	Resolver          *ResolverConfig        `json:"resolver"`
+	DnsStub           *DnsStubConfig         `json:"dns_stub"`
+	TlsPinTofu        bool                   `json:"tls_pin_tofu"`
+	Proxy             *types.Proxy           `json:"proxy"`
+	EnclavePrivateKey string                 `json:"enclave_private_key"`
+	AccessPolicy      *AccessPolicy          `json:"access_policy"`
+	ReconnectPolicy   *types.ReconnectPolicy `json:"reconnect_policy"`
 }
 
 func (c *ConfigData) Save() (err error) {
@@ -75,7 +127,9 @@ func Load() (err error) {
 	if !exists {
 		err = nil
 		data.loaded = true
+		lock.Lock()
 		Config = data
+		lock.Unlock()
 		return
 	}
 
@@ -97,7 +151,9 @@ func Load() (err error) {
 
 	data.loaded = true
 
+	lock.Lock()
 	Config = data
+	lock.Unlock()
 
 	if move {
 		newPath := GetPath()
@@ -116,6 +172,14 @@ func Load() (err error) {
 	return
 }
 
+// GetAccessPolicy returns the access policy of the loaded config
+func GetAccessPolicy() *AccessPolicy {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return Config.AccessPolicy
+}
+
 func Save() (err error) {
 	err = Config.Save()
 	if err != nil {
@@ -29,6 +29,8 @@ import (
 	"github.com/pritunl/pritunl-client-electron/service/constants"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
 	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
 	"github.com/pritunl/pritunl-client-electron/service/tpm"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
@@ -43,6 +45,8 @@ const (
 
 var (
 	clientTransport = &http.Transport{
+		Proxy:               proxy.Func,
+		DialContext:         dialContext,
 		DisableKeepAlives:   true,
 		TLSHandshakeTimeout: 8 * time.Second,
 		TLSClientConfig: &tls.Config{
@@ -135,12 +139,14 @@ type Client struct {
 	conn              *Connection
 	prov              Provider
 	requestCtxLock    sync.Mutex
-	requestCtx        *utils.CancelContext
+	requestCtxs       map[*utils.CancelContext]bool
+	tpmLock           sync.Mutex
 	disconnectLock    sync.Mutex
 	disconnect        bool
 	disconnected      bool
 	disconnectWaiters []chan bool
 	startTime         time.Time
+	remote            string
 }
 
 func (c *Client) Fields() logrus.Fields {
@@ -249,6 +255,8 @@ func (c *Client) globalTimeout(timeout time.Duration) {
 			"global_timeout": timeout.Seconds(),
 		})).Error("profile: Global connection timeout")
 
+		c.conn.Data.recordHandshake(false)
+
 		c.Disconnect()
 	}
 }
@@ -277,33 +285,42 @@ func (c *Client) connectPreAuth() (err error) {
 
 	go c.globalTimeout(GlobalTimeoutPreAuth)
 
-	for _, remote := range c.conn.Data.Remotes {
-		logrus.WithFields(c.conn.Fields(logrus.Fields{
-			"remote": remote.GetFormatted(),
-		})).Info("connection: Attempting remote")
+	if c.isRaceRemotes() {
+		data, evt, connErrors = c.raceRemotes()
+	} else {
+		for _, remote := range c.conn.Data.Remotes {
+			logrus.WithFields(c.conn.Fields(logrus.Fields{
+				"remote": remote.GetFormatted(),
+			})).Info("connection: Attempting remote")
 
-		if c.conn.State.IsStop() {
-			c.conn.State.Close()
-			return
-		}
+			if c.conn.State.IsStop() {
+				c.conn.State.Close()
+				return
+			}
 
-		data, final, evt, err = c.authorize(remote.Host, "", time.Time{})
-		if err != nil {
-			connErrors = append(connErrors, ConnectionError{
-				Host:  remote.Host,
-				Error: err,
-			})
-			err = nil
-			if final {
+			c.remote = remote.Host
+			authStart := time.Now()
+			data, final, evt, err = c.authorizeRemote(nil, remote)
+			authResult := getAuthResult(data, evt, err)
+			GlobalMetrics.Authorize(c.conn.Id, remote.Host, authResult)
+			c.recordAuthorize(remote.Host, authResult, authStart)
+			if err != nil {
+				connErrors = append(connErrors, ConnectionError{
+					Host:  remote.Host,
+					Error: err,
+				})
+				err = nil
+				if final {
+					break
+				}
+			} else {
 				break
 			}
-		} else {
-			break
-		}
 
-		if c.conn.State.IsStop() {
-			c.conn.State.Close()
-			return
+			if c.conn.State.IsStop() {
+				c.conn.State.Close()
+				return
+			}
 		}
 	}
 
@@ -363,7 +380,8 @@ func (c *Client) connectPreAuth() (err error) {
 			})).Error("profile: Device registration required")
 
 			c.conn.Data.RegistrationKey = data.RegKey
-			c.conn.State.NoReconnect("client_device_registration")
+			c.conn.State.Failure(RegistrationFailure,
+				"client_device_registration")
 
 			if c.conn.Profile.SystemProfile {
 				sprofile.Deactivate(c.conn.Profile.Id)
@@ -389,7 +407,7 @@ func (c *Client) connectPreAuth() (err error) {
 				"reason": data.Reason,
 			})).Error("profile: Failed to authenticate")
 
-			c.conn.State.NoReconnect("client_auth_error")
+			c.conn.State.Failure(AuthFailure, "client_auth_error")
 			c.conn.Data.SendProfileEvent("auth_error")
 
 			if c.conn.Profile.SystemProfile {
@@ -444,6 +462,70 @@ func (c *Client) connectPreAuth() (err error) {
 	return
 }
 
+// authorizeRemote attempts each resolved address of the remote until a
+// response is received from the server
+func (c *Client) authorizeRemote(ctx *utils.CancelContext,
+	remote *Remote) (data *ConnData, final bool, evt *event.Event,
+	err error) {
+
+	addrs := remote.GetAddrs()
+	if len(addrs) == 0 ||
+		proxy.IsActive(c.conn.Profile.Proxy, ParseAddress(remote.Host)) {
+
+		addrs = []string{""}
+	}
+
+	for i, addr := range addrs {
+		if c.conn.State.IsStop() {
+			return
+		}
+
+		if addr != "" && len(addrs) > 1 {
+			logrus.WithFields(c.conn.Fields(logrus.Fields{
+				"remote":  remote.Host,
+				"address": addr,
+			})).Info("connection: Attempting remote address")
+		}
+
+		data, final, evt, err = c.authorize(
+			ctx, remote.Host, addr, "", time.Time{})
+		if err == nil || evt != nil || final || i == len(addrs)-1 {
+			return
+		}
+
+		logrus.WithFields(c.conn.Fields(logrus.Fields{
+			"remote":  remote.Host,
+			"address": addr,
+			"error":   err,
+		})).Info("connection: Remote address failed, trying next address")
+	}
+
+	return
+}
+
+func getAuthResult(data *ConnData, evt *event.Event, err error) string {
+	if err != nil {
+		if evt != nil {
+			switch evt.Type {
+			case "offline_error":
+				return AuthOffline
+			case "timeout_error":
+				return AuthSsoTimeout
+			}
+		}
+		return AuthError
+	}
+
+	if data == nil {
+		return AuthError
+	}
+
+	if data.Allow {
+		return AuthSuccess
+	}
+	return AuthDenied
+}
+
 func (c *Client) GetUrl(scheme, host, handle string) *url.URL {
 	reqPath := fmt.Sprintf(
 		"/key/%s/%s/%s/%s",
@@ -524,8 +606,8 @@ func (c *Client) InitBox() (ciph *Cipher, reqBx *ReqBox, err error) {
 	return
 }
 
-func (c *Client) authorize(host string, ssoToken string,
-	ssoStart time.Time) (data *ConnData, final bool,
+func (c *Client) authorize(ctx *utils.CancelContext, host, addr string,
+	ssoToken string, ssoStart time.Time) (data *ConnData, final bool,
 	evt *event.Event, err error) {
 
 	tokn, err := c.conn.Data.GetAuthToken()
@@ -561,10 +643,13 @@ func (c *Client) authorize(host string, ssoToken string,
 		return
 	}
 
-	ctx := c.GetContext()
-	defer ctx.Cancel()
+	if ctx == nil {
+		ctx = c.GetContext()
+		defer ctx.Cancel()
+	}
 
-	res, err := c.EncRequest(ctx, "POST", reqUrl, ciph, reqBx)
+	res, tofuPin, err := c.EncRequest(withDialAddr(ctx, addr), "POST",
+		reqUrl, ciph, reqBx)
 	if err != nil {
 		return
 	}
@@ -583,7 +668,8 @@ func (c *Client) authorize(host string, ssoToken string,
 			return
 		}
 
-		data, _, evt, err = c.authorize(host, ssoToken, ssoStart)
+		data, _, evt, err = c.authorize(
+			nil, host, addr, ssoToken, ssoStart)
 		if err != nil {
 			return
 		}
@@ -669,11 +755,11 @@ func (c *Client) authorize(host string, ssoToken string,
 			c.conn.Data.SsoUrl = respBx.SsoUrl
 		}
 
-		c.conn.Data.Status = "authenticating"
+		c.conn.Data.SetStatus(Authenticating, "sso_auth")
 		c.conn.Data.UpdateEvent()
 
 		data, _, evt, err = c.authorize(
-			host, respBx.SsoToken, time.Now())
+			nil, host, addr, respBx.SsoToken, time.Now())
 		if err != nil {
 			return
 		}
@@ -690,7 +776,7 @@ func (c *Client) authorize(host string, ssoToken string,
 		final = true
 		return
 	} else if ssoToken != "" {
-		c.conn.Data.Status = "connecting"
+		c.conn.Data.SetStatus(Connecting, "sso_complete")
 		c.conn.Data.UpdateEvent()
 	}
 
@@ -705,6 +791,8 @@ func (c *Client) authorize(host string, ssoToken string,
 		return
 	}
 
+	c.storeTlsPin(reqUrl.Hostname(), tofuPin)
+
 	return
 }
 
@@ -727,6 +815,9 @@ func (c *Client) encryptReqBox(method string, reqPath string,
 	}
 
 	if c.conn.Profile.DeviceAuth && method == "POST" {
+		c.tpmLock.Lock()
+		defer c.tpmLock.Unlock()
+
 		err = tp.Open(config.Config.EnclavePrivateKey)
 		if err != nil {
 			return
@@ -944,14 +1035,15 @@ func (c *Client) GetContext() (ctx *utils.CancelContext) {
 	ctx = utils.NewCancelContext()
 	ctx.OnCancel(func() {
 		c.requestCtxLock.Lock()
-		if c.requestCtx == ctx {
-			c.requestCtx = nil
-		}
+		delete(c.requestCtxs, ctx)
 		c.requestCtxLock.Unlock()
 	})
 
 	c.requestCtxLock.Lock()
-	c.requestCtx = ctx
+	if c.requestCtxs == nil {
+		c.requestCtxs = map[*utils.CancelContext]bool{}
+	}
+	c.requestCtxs[ctx] = true
 	c.requestCtxLock.Unlock()
 
 	return
@@ -969,17 +1061,23 @@ func (c *Client) CancelRequest() {
 	}()
 
 	c.requestCtxLock.Lock()
-	ctx := c.requestCtx
+	ctxs := []*utils.CancelContext{}
+	for ctx := range c.requestCtxs {
+		ctxs = append(ctxs, ctx)
+	}
 	c.requestCtxLock.Unlock()
 
-	if ctx != nil {
+	for _, ctx := range ctxs {
 		ctx.Cancel()
 	}
 }
 
+// EncRequest sends the encrypted request, tofuPin is set when the host is
+// trusted on first use and should only be stored once the response has
+// been authenticated
 func (c *Client) EncRequest(ctx context.Context, method string,
 	reqUrl *url.URL, ciph *Cipher, reqBx *ReqBox) (
-	resp *http.Response, err error) {
+	resp *http.Response, tofuPin string, err error) {
 
 	encReqData, err := c.encryptReqBox(method, reqUrl.Path, ciph, reqBx)
 	if err != nil {
@@ -987,7 +1085,7 @@ func (c *Client) EncRequest(ctx context.Context, method string,
 	}
 
 	req, err := http.NewRequestWithContext(
-		ctx,
+		proxy.WithProfile(ctx, c.conn.Profile.Proxy),
 		method,
 		reqUrl.String(),
 		bytes.NewBuffer(encReqData.Body),
@@ -1006,8 +1104,9 @@ func (c *Client) EncRequest(ctx context.Context, method string,
 	req.Header.Set("Auth-Nonce", encReqData.Nonce)
 	req.Header.Set("Auth-Signature", encReqData.Signature)
 
-	resp, err = clientInsecure.Do(req)
+	resp, err = c.getHttpClient(reqUrl.Hostname(), &tofuPin).Do(req)
 	if err != nil {
+		tofuPin = ""
 		err = &errortypes.RequestError{
 			errors.Wrap(err, "profile: Request put error"),
 		}
@@ -1038,7 +1137,7 @@ func (c *Client) Disconnect() {
 	logrus.WithFields(c.conn.Fields(nil)).Error(
 		"connection: Disconnecting")
 
-	c.conn.Data.Status = "disconnecting"
+	c.conn.Data.SetStatus(Disconnecting, "disconnect")
 	c.conn.Data.UpdateEvent()
 
 	c.CancelRequest()
@@ -1065,9 +1164,13 @@ func (c *Client) Disconnect() {
 		}
 	}
 
+	c.conn.Data.disableIpv6Guard()
+	c.conn.Data.clearDns()
 	c.conn.State.RemovePaths()
+	c.conn.State.clearJournal()
 
-	c.conn.Data.Status = "disconnected"
+	c.conn.Data.SetStatus(Disconnected, "disconnect")
+	c.conn.Data.runHook(hooks.Disconnected)
 	c.conn.Data.Clear()
 	c.conn.Data.UpdateEvent()
 
@@ -1086,9 +1189,19 @@ func (c *Client) Disconnect() {
 
 func (c *Client) Disconnected() {
 	if c.conn.State.IsReconnect() {
-		logrus.WithFields(c.conn.Fields(nil)).Info(
-			"profile: Disconnected with restart")
-		go c.conn.Restart()
+		delay, generation, ok := GlobalBackoff.Next(c.conn.Id,
+			c.conn.Profile.GetReconnectPolicy(), c.conn.State.GetFailure(),
+			!c.conn.Profile.HasReconnectPolicy())
+		if !ok {
+			logrus.WithFields(c.conn.Fields(nil)).Warn(
+				"profile: Disconnected with reconnect attempts exhausted")
+			return
+		}
+
+		logrus.WithFields(c.conn.Fields(logrus.Fields{
+			"reconnect_delay": delay.String(),
+		})).Info("profile: Disconnected with restart")
+		go c.conn.RestartDelay(delay, generation, true)
 	} else {
 		logrus.WithFields(c.conn.Fields(nil)).Info(
 			"profile: Disconnected without restart")
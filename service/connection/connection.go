@@ -3,6 +3,7 @@ package connection
 import (
 	"runtime"
 	"runtime/debug"
+	"time"
 
 	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/event"
@@ -158,6 +159,53 @@ func (c *Connection) Restart() {
 	}
 }
 
+// RestartDelay waits for the reconnect delay and restarts the connection,
+// the restart is cancelled if the profile is stopped or started elsewhere.
+// Reconnect counts the restart in the reconnect metrics.
+func (c *Connection) RestartDelay(delay time.Duration, generation int,
+	reconnect bool) {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(c.Fields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			})).Error("profile: Restart delay panic")
+		}
+	}()
+
+	start := time.Now()
+	for time.Since(start) < delay {
+		time.Sleep(250 * time.Millisecond)
+
+		if Shutdown || GlobalStore.IsStop(c.Id) ||
+			!GlobalBackoff.IsCurrent(c.Id, generation) {
+
+			GlobalBackoff.Start(c.Id, generation)
+			return
+		}
+	}
+
+	if !GlobalBackoff.Start(c.Id, generation) ||
+		GlobalStore.Get(c.Id) != nil {
+
+		return
+	}
+
+	if c.Profile.SystemProfile {
+		sprfl := sprofile.Get(c.Id)
+		if sprfl == nil || !sprfl.State {
+			return
+		}
+	}
+
+	if reconnect {
+		GlobalMetrics.Reconnect(c.Id)
+	}
+
+	c.Restart()
+}
+
 func (c *Connection) Stop() {
 	c.State.NoReconnect("stop")
 	c.Client.Disconnect()
@@ -3,6 +3,7 @@ package connection
 import (
 	"time"
 
+	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
@@ -15,6 +16,11 @@ const (
 	OvpnMode            = "ovpn"
 	WgMode              = "wg"
 	NmOvpnUser          = "nm-openvpn"
+	LatencySort         = "latency"
+	HealthSort          = "health"
+	LatencySortTimeout  = 2 * time.Second
+	LatencySortTtl      = 300 * time.Second
+	PauseMaxDuration    = 7 * 24 * time.Hour
 )
 
 var (
@@ -31,6 +37,13 @@ func SetShutdown() {
 	sprofile.Shutdown()
 }
 
+func getLatencySortTtl() time.Duration {
+	if config.Config.LatencySortTtl > 0 {
+		return time.Duration(config.Config.LatencySortTtl) * time.Second
+	}
+	return LatencySortTtl
+}
+
 type ConnectionError struct {
 	Host  string
 	Error error
@@ -14,17 +14,13 @@ import (
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
 	"github.com/pritunl/pritunl-client-electron/service/event"
 	"github.com/pritunl/pritunl-client-electron/service/geosort"
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
 	"github.com/pritunl/pritunl-client-electron/service/token"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	Connecting    = "connecting"
-	Connected     = "connected"
-	Disconnecting = "disconnecting"
-	Disconnected  = "disconnected"
-
 	OvpnRemote = "ovpn"
 	SyncRemote = "sync"
 )
@@ -47,7 +43,7 @@ type Data struct {
 	WgTunIface       string      `json:"tun_iface"`
 	Routes           []*Route    `json:"routes"`
 	Routes6          []*Route    `json:"routes6"`
-	Status           string      `json:"status"`
+	Status           Status      `json:"status"`
 	Timestamp        int64       `json:"timestamp"`
 	GatewayAddr      string      `json:"gateway_addr"`
 	GatewayAddr6     string      `json:"gateway_addr6"`
@@ -69,10 +65,18 @@ type Data struct {
 	PublicAddr       string      `json:"public_addr"`
 	PublicAddr6      string      `json:"public_addr6"`
 	Remotes          Remotes     `json:"remotes"`
+	Ipv6GuardActive  bool        `json:"ipv6_guard_active"`
 	DefaultOvpnPort  int         `json:"-"`
 	DefaultOvpnProto string      `json:"-"`
 	macAddrs         []string    `json:"-"`
+	dnsIface         string      `json:"-"`
 	authToken        *AuthToken  `json:"-"`
+	historyStatus    Status      `json:"-"`
+	historyRemote    string      `json:"-"`
+	historyConnected time.Time   `json:"-"`
+	historyLock      sync.Mutex
+	authTokenLock    sync.Mutex
+	statusMachine    statusMachine
 }
 
 type Route struct {
@@ -315,16 +319,46 @@ func (d *Data) ParseProfile() (err error) {
 		d.PublicAddr6 = addr6
 	}
 
-	sortMethod := ""
+	append(syncRemotes, remotes...).Lookup()
+	d.lookupKillSwitch(append(syncRemotes, remotes...))
+
+	newRemotes := Remotes{}
+	tiers := groupPriority(d.conn.Profile.RemotesData, remotes, syncRemotes)
+	for _, tier := range tiers {
+		tierRemotes, sortMethod := d.sortRemotes(
+			tier.remotes, tier.syncRemotes)
+		newRemotes = append(newRemotes, tierRemotes...)
+
+		logrus.WithFields(logrus.Fields{
+			"priority":    tier.priority,
+			"sort_method": sortMethod,
+			"remotes":     tierRemotes.GetFormatted(),
+		}).Info("connection: Sorted priority tier")
+	}
+	remotes = newRemotes
+
+	logrus.WithFields(logrus.Fields{
+		"public_address":  d.PublicAddr,
+		"public_address6": d.PublicAddr6,
+		"priority_tiers":  len(tiers),
+		"remotes":         remotes.GetFormatted(),
+	}).Info("connection: Resolved remotes")
+
+	d.Remotes = remotes
+	d.updateKillSwitch()
+
+	return
+}
+
+// sortRemotes orders the remotes of a single priority tier
+func (d *Data) sortRemotes(remotes, syncRemotes Remotes) (
+	sorted Remotes, sortMethod string) {
+
 	if d.conn.Profile.IsGeoSort() {
 		remotes = append(syncRemotes, remotes...)
 
 		sortMethod = "geo"
 
-		for _, remote := range remotes {
-			remote.Lookup()
-		}
-
 		remoteHosts := geosort.SortRemotes(
 			d.PublicAddr, d.PublicAddr6, remotes.GetAddrs(),
 			d.conn.Profile.GeoSort)
@@ -347,7 +381,49 @@ func (d *Data) ParseProfile() (err error) {
 			newRemotes = append(newRemotes, remote)
 		}
 
-		remotes = newRemotes
+		remotes = newRemotes.DemoteFailing(d.conn.Id)
+	} else if d.conn.Profile.IsLatencySort() {
+		remotes = append(syncRemotes, remotes...)
+
+		sortMethod = "latency"
+
+		addrs := []string{}
+		addrMap := map[string]*Remote{}
+		otherRemotes := Remotes{}
+		for _, remote := range remotes {
+			addr := ParseAddress(remote.Host)
+			if addr == "" || addrMap[addr] != nil {
+				otherRemotes = append(otherRemotes, remote)
+				continue
+			}
+			addrs = append(addrs, addr)
+			addrMap[addr] = remote
+		}
+
+		newRemotes := Remotes{}
+		for _, addr := range geosort.LatencySort(d.conn.Profile.Proxy,
+			addrs, LatencySortTimeout, getLatencySortTtl()) {
+
+			newRemotes = append(newRemotes, addrMap[addr])
+		}
+		newRemotes = append(newRemotes, otherRemotes...)
+
+		remotes = newRemotes.DemoteFailing(d.conn.Id)
+	} else if d.conn.Profile.IsHealthSort() {
+		sortMethod = "health"
+		newSyncRemotes := Remotes{}
+		newRemotes := Remotes{}
+
+		for _, i := range mathrand.Perm(len(syncRemotes)) {
+			newSyncRemotes = append(newSyncRemotes, syncRemotes[i])
+		}
+
+		for _, i := range mathrand.Perm(len(remotes)) {
+			newRemotes = append(newRemotes, remotes[i])
+		}
+
+		remotes = append(newSyncRemotes.SortHealth(d.conn.Id),
+			newRemotes.SortHealth(d.conn.Id)...)
 	} else {
 		sortMethod = "random"
 		newRemotes := Remotes{}
@@ -363,14 +439,7 @@ func (d *Data) ParseProfile() (err error) {
 		remotes = newRemotes
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"public_address":  d.PublicAddr,
-		"public_address6": d.PublicAddr6,
-		"sort_method":     sortMethod,
-		"remotes":         remotes.GetFormatted(),
-	}).Info("connection: Resolved remotes")
-
-	d.Remotes = remotes
+	sorted = remotes
 
 	return
 }
@@ -396,21 +465,31 @@ func (t *AuthToken) Validate() {
 }
 
 func (d *Data) ResetAuthToken() {
+	d.authTokenLock.Lock()
 	authToken := d.authToken
+	d.authTokenLock.Unlock()
+
 	if authToken != nil {
 		authToken.Reset()
 	}
 }
 
 func (d *Data) ValidateAuthToken() {
+	d.authTokenLock.Lock()
 	authToken := d.authToken
+	d.authTokenLock.Unlock()
+
 	if authToken != nil {
 		authToken.Validate()
 	}
 }
 
 func (d *Data) HasAuthToken() bool {
-	if d.authToken != nil {
+	d.authTokenLock.Lock()
+	authToken := d.authToken
+	d.authTokenLock.Unlock()
+
+	if authToken != nil {
 		return true
 	}
 
@@ -422,7 +501,12 @@ func (d *Data) HasAuthToken() bool {
 	return tokn != nil
 }
 
+// GetAuthToken returns the auth token of the connection, the token is
+// created on the first call and shared by the parallel remote attempts
 func (d *Data) GetAuthToken() (authToken *AuthToken, err error) {
+	d.authTokenLock.Lock()
+	defer d.authTokenLock.Unlock()
+
 	if d.authToken != nil {
 		authToken = d.authToken
 		return
@@ -483,6 +567,11 @@ func (d *Data) GetAuthToken() (authToken *AuthToken, err error) {
 }
 
 func (d *Data) SendProfileEvent(evtType string) {
+	d.recordEvent(evtType)
+	if evtType == "auth_error" {
+		d.runHook(hooks.AuthFailure)
+	}
+
 	eventLock.Lock()
 	limit := eventLimits[evtType]
 	if limit != 0 {
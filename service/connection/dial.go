@@ -0,0 +1,40 @@
+package connection
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/resolver"
+)
+
+type dialAddrKey struct{}
+
+var clientDialer = &net.Dialer{
+	Timeout:   10 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+// withDialAddr returns a context that connects requests to the address
+// instead of resolving the request host
+func withDialAddr(ctx context.Context, addr string) context.Context {
+	if addr == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, dialAddrKey{}, addr)
+}
+
+func dialContext(ctx context.Context, network, addr string) (
+	net.Conn, error) {
+
+	dialAddr, _ := ctx.Value(dialAddrKey{}).(string)
+	if dialAddr != "" {
+		_, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			return clientDialer.DialContext(ctx, network,
+				net.JoinHostPort(dialAddr, port))
+		}
+	}
+
+	return resolver.DialContext(ctx, network, addr)
+}
@@ -0,0 +1,89 @@
+package connection
+
+import (
+	"strings"
+
+	"github.com/pritunl/pritunl-client-electron/service/dns"
+	"github.com/pritunl/pritunl-client-electron/service/dnsstub"
+	"github.com/pritunl/pritunl-client-electron/service/journal"
+	"github.com/sirupsen/logrus"
+)
+
+// parseOvpnDns returns the DNS servers and search domains of the OpenVPN
+// dhcp options
+func parseOvpnDns(opts []string) (servers, domains []string) {
+	servers = []string{}
+	domains = []string{}
+
+	for _, opt := range opts {
+		fields := strings.Fields(opt)
+		if len(fields) < 3 || fields[0] != "dhcp-option" {
+			continue
+		}
+
+		switch strings.ToUpper(fields[1]) {
+		case "DNS", "DNS6":
+			servers = append(servers, fields[2])
+		case "DOMAIN", "DOMAIN-SEARCH":
+			domains = append(domains, fields[2])
+		}
+	}
+
+	return
+}
+
+// setDns configures the DNS servers and search domains of the tunnel
+// interface with the system resolver, the DNS stub is used as the only
+// server when running unless the backend is link scoped, in which case
+// the stub is set as the global server by the DNS stub watch
+func (d *Data) setDns(iface string, servers, domains []string) {
+	if d.conn.Profile.DisableDns || !dns.Supported || iface == "" {
+		return
+	}
+
+	if len(servers) == 0 && len(domains) == 0 {
+		return
+	}
+
+	force := d.conn.Profile.ForceDns
+	if dnsstub.IsRunning() && !dns.IsLinkScoped() {
+		servers = []string{dnsstub.GetHost()}
+		force = true
+	}
+
+	d.conn.State.addJournal(journal.DnsEntry, iface, dns.GetBackend())
+
+	err := dns.Set(&dns.Config{
+		Iface:         iface,
+		Servers:       servers,
+		SearchDomains: domains,
+		Force:         force,
+	})
+	if err != nil {
+		logrus.WithFields(d.conn.Fields(logrus.Fields{
+			"iface": iface,
+			"error": err,
+		})).Error("connection: Failed to configure DNS")
+		return
+	}
+
+	d.dnsIface = iface
+}
+
+func (d *Data) clearDns() {
+	if d.dnsIface == "" {
+		return
+	}
+
+	err := dns.Clear(d.dnsIface)
+	if err != nil {
+		logrus.WithFields(d.conn.Fields(logrus.Fields{
+			"iface": d.dnsIface,
+			"error": err,
+		})).Error("connection: Failed to clear DNS")
+		return
+	}
+
+	d.conn.State.removeJournal(journal.DnsEntry, d.dnsIface)
+	d.dnsIface = ""
+}
@@ -0,0 +1,29 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/health"
+)
+
+func (c *Client) recordAuthorize(host, result string, start time.Time) {
+	if result == AuthSsoTimeout || c.conn.State.IsStopFast() {
+		return
+	}
+
+	go health.Authorize(c.conn.Id, host, result, time.Since(start))
+}
+
+func (d *Data) recordHandshake(success bool) {
+	if d.conn == nil || d.conn.Client == nil || d.conn.State == nil {
+		return
+	}
+
+	remote := d.conn.Client.remote
+	if remote == "" {
+		return
+	}
+
+	go health.Handshake(d.Id, remote, success,
+		time.Since(d.conn.State.startTime))
+}
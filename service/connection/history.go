@@ -0,0 +1,80 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/history"
+)
+
+func (d *Data) historyRecord(typ, state string) *history.Record {
+	record := &history.Record{
+		Timestamp: time.Now(),
+		ProfileId: d.Id,
+		Mode:      d.Mode,
+		Type:      typ,
+		State:     state,
+		Remote:    d.ServerAddr,
+	}
+
+	if d.conn == nil {
+		return record
+	}
+
+	if record.Mode == "" && d.conn.Profile != nil {
+		record.Mode = d.conn.Profile.Mode
+	}
+	if record.Remote == "" && d.conn.Client != nil {
+		record.Remote = d.conn.Client.remote
+	}
+	if d.conn.State != nil {
+		record.Reason = d.conn.State.noReconnectReason
+		if !d.conn.State.startTime.IsZero() {
+			record.Duration = int64(
+				time.Since(d.conn.State.startTime).Seconds())
+		}
+	}
+
+	return record
+}
+
+// recordStatus queues the status record, it is called with the status lock
+// held so records are queued in the order of the transitions and written
+// by the history writer outside of the lock
+func (d *Data) recordStatus(status Status) {
+	d.historyLock.Lock()
+	defer d.historyLock.Unlock()
+
+	if status == "" || status == d.historyStatus {
+		return
+	}
+	d.historyStatus = status
+
+	record := d.historyRecord(history.StatusRecord, string(status))
+
+	switch status {
+	case Connected:
+		d.historyConnected = record.Timestamp
+		d.historyRemote = record.Remote
+	case Disconnected:
+		if record.Remote == "" {
+			record.Remote = d.historyRemote
+		}
+		if d.historyConnected.IsZero() {
+			record.Duration = 0
+		} else {
+			record.Duration = int64(
+				record.Timestamp.Sub(d.historyConnected).Seconds())
+		}
+		d.historyConnected = time.Time{}
+	}
+
+	history.Add(record)
+}
+
+func (d *Data) recordEvent(evtType string) {
+	d.historyLock.Lock()
+	defer d.historyLock.Unlock()
+
+	record := d.historyRecord(history.EventRecord, evtType)
+	history.Add(record)
+}
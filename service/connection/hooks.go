@@ -0,0 +1,31 @@
+package connection
+
+import (
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
+)
+
+func (d *Data) runHook(hook string) {
+	data := &hooks.Data{
+		ProfileId:  d.Id,
+		Mode:       d.Mode,
+		Iface:      d.Iface,
+		ClientAddr: d.ClientAddr,
+		ServerAddr: d.ServerAddr,
+		DnsServers: append([]string{}, d.DnsServers...),
+		Routes:     []string{},
+		Routes6:    []string{},
+	}
+
+	if data.Mode == "" && d.conn != nil && d.conn.Profile != nil {
+		data.Mode = d.conn.Profile.Mode
+	}
+
+	for _, route := range d.Routes {
+		data.Routes = append(data.Routes, route.Network)
+	}
+	for _, route := range d.Routes6 {
+		data.Routes6 = append(data.Routes6, route.Network)
+	}
+
+	hooks.Run(hook, data)
+}
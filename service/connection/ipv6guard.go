@@ -0,0 +1,202 @@
+package connection
+
+import (
+	"net"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-client-electron/service/ipv6guard"
+	"github.com/pritunl/pritunl-client-electron/service/journal"
+	"github.com/sirupsen/logrus"
+)
+
+// parseOvpnRoutes returns true for each address family that is fully
+// routed through the tunnel by the OpenVPN options, this includes the def1
+// split routes when both halves of the address space are routed
+func parseOvpnRoutes(opts []string) (full4, full6 bool) {
+	routes, routes6 := parseOvpnRouteList(opts)
+
+	networks := set.NewSet()
+	for _, route := range append(routes, routes6...) {
+		if route.NetGateway {
+			continue
+		}
+		networks.Add(route.Network)
+	}
+
+	full4 = networks.Contains("0.0.0.0/0") ||
+		(networks.Contains("0.0.0.0/1") &&
+			networks.Contains("128.0.0.0/1"))
+	full6 = networks.Contains("::/0") ||
+		networks.Contains("2000::/3") ||
+		(networks.Contains("::/1") && networks.Contains("8000::/1"))
+
+	return
+}
+
+// parseOvpnRouteList returns the networks routed by the OpenVPN options
+func parseOvpnRouteList(opts []string) (routes, routes6 []*Route) {
+	routes = []*Route{}
+	routes6 = []*Route{}
+
+	for _, opt := range opts {
+		fields := strings.Fields(opt)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "redirect-gateway":
+			ipv4 := true
+			ipv6 := false
+			for _, flag := range fields[1:] {
+				switch flag {
+				case "!ipv4":
+					ipv4 = false
+				case "ipv6":
+					ipv6 = true
+				}
+			}
+			if ipv4 {
+				routes = append(routes, &Route{
+					Network: "0.0.0.0/0",
+				})
+			}
+			if ipv6 {
+				routes6 = append(routes6, &Route{
+					Network: "::/0",
+				})
+			}
+		case "route":
+			if len(fields) < 2 {
+				continue
+			}
+
+			ip := net.ParseIP(fields[1]).To4()
+			if ip == nil {
+				continue
+			}
+
+			size := 32
+			if len(fields) >= 3 {
+				mask := net.ParseIP(fields[2]).To4()
+				if mask == nil {
+					continue
+				}
+				size, _ = net.IPMask(mask).Size()
+			}
+
+			route := &Route{
+				Network: (&net.IPNet{
+					IP:   ip.Mask(net.CIDRMask(size, 32)),
+					Mask: net.CIDRMask(size, 32),
+				}).String(),
+			}
+			if len(fields) >= 4 {
+				if fields[3] == "net_gateway" {
+					route.NetGateway = true
+				} else if fields[3] != "vpn_gateway" {
+					route.NextHop = fields[3]
+				}
+			}
+			routes = append(routes, route)
+		case "route-ipv6":
+			if len(fields) < 2 {
+				continue
+			}
+
+			_, network, err := net.ParseCIDR(fields[1])
+			if err != nil {
+				continue
+			}
+
+			route := &Route{
+				Network: network.String(),
+			}
+			if len(fields) >= 3 {
+				if fields[2] == "net_gateway" {
+					route.NetGateway = true
+				} else if fields[2] != "vpn_gateway" {
+					route.NextHop = fields[2]
+				}
+			}
+			routes6 = append(routes6, route)
+		}
+	}
+
+	return
+}
+
+// parseOvpnPush returns the options of a pushed reply in the OpenVPN output
+func parseOvpnPush(line string) (opts []string) {
+	index := strings.Index(line, "PUSH_REPLY,")
+	if index == -1 {
+		return
+	}
+
+	reply := line[index+len("PUSH_REPLY,"):]
+	reply = strings.TrimSpace(reply)
+	reply = strings.TrimSuffix(reply, "'")
+	opts = strings.Split(reply, ",")
+
+	return
+}
+
+// hasWgRoute returns true if the network is routed through the tunnel
+func hasWgRoute(routes []*Route, network string) bool {
+	for _, route := range routes {
+		if route.Network == network && !route.NetGateway {
+			return true
+		}
+	}
+	return false
+}
+
+// updateIpv6Guard blocks global IPv6 traffic outside of the tunnel when the
+// connection routes all IPv4 traffic without routing IPv6 traffic, without
+// the guard IPv6 traffic would bypass the tunnel
+func (d *Data) updateIpv6Guard(full4, full6 bool) {
+	if !d.conn.Profile.Ipv6Guard || !ipv6guard.Supported {
+		return
+	}
+
+	if !full4 || full6 {
+		d.disableIpv6Guard()
+		return
+	}
+
+	addrs := []string{}
+	for _, remote := range d.Remotes {
+		addrs = append(addrs, remote.GetAddrs6()...)
+	}
+
+	d.conn.State.addJournal(journal.Ipv6GuardEntry, d.Id, "")
+
+	err := ipv6guard.Enable(d.Id, addrs, d.Iface)
+	if err != nil {
+		logrus.WithFields(d.conn.Fields(logrus.Fields{
+			"error": err,
+		})).Error("connection: Failed to enable IPv6 leak guard")
+		return
+	}
+
+	d.Ipv6GuardActive = true
+}
+
+func (d *Data) disableIpv6Guard() {
+	if !ipv6guard.IsActive(d.Id) {
+		d.Ipv6GuardActive = false
+		return
+	}
+
+	err := ipv6guard.Disable(d.Id)
+	if err != nil {
+		logrus.WithFields(d.conn.Fields(logrus.Fields{
+			"error": err,
+		})).Error("connection: Failed to disable IPv6 leak guard")
+		return
+	}
+
+	d.Ipv6GuardActive = false
+	d.conn.State.removeJournal(journal.Ipv6GuardEntry, d.Id)
+}
@@ -0,0 +1,74 @@
+package connection
+
+import (
+	"runtime"
+
+	"github.com/pritunl/pritunl-client-electron/service/journal"
+)
+
+func (s *State) addJournal(typ, value, pth string) {
+	journal.Add(s.id, s.conn.Id, typ, value, pth)
+}
+
+func (s *State) addJournalAll(ents []*journal.Entry) {
+	journal.AddAll(s.id, s.conn.Id, ents)
+}
+
+func (s *State) removeJournal(typ, value string) {
+	journal.Remove(s.id, typ, value)
+}
+
+func (s *State) removeJournalAll(ents []*journal.Entry) {
+	journal.RemoveAll(s.id, ents)
+}
+
+func (s *State) clearJournal() {
+	journal.Clear(s.id)
+}
+
+// wgJournalEntries returns the interface entry and the entries of the
+// routes added to the interface
+func (w *Wg) wgJournalEntries(routes, routes6 []*Route) (
+	ents []*journal.Entry) {
+
+	iface := w.conn.Data.Iface
+
+	ents = []*journal.Entry{
+		{
+			Type:  journal.WgIfaceEntry,
+			Value: iface,
+			Path:  w.wgQuickPath,
+		},
+	}
+
+	for _, route := range append(routes, routes6...) {
+		if route.NetGateway {
+			continue
+		}
+		ents = append(ents, &journal.Entry{
+			Type:  journal.RouteEntry,
+			Value: route.Network,
+			Path:  iface,
+		})
+	}
+
+	return
+}
+
+func (w *Wg) addJournal(data *WgConf) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	w.conn.State.addJournalAll(
+		w.wgJournalEntries(data.Routes, data.Routes6))
+}
+
+func (w *Wg) removeJournal() {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	w.conn.State.removeJournalAll(
+		w.wgJournalEntries(w.conn.Data.Routes, w.conn.Data.Routes6))
+}
@@ -0,0 +1,149 @@
+package connection
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/pritunl/pritunl-client-electron/service/killswitch"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
+	"github.com/pritunl/pritunl-client-electron/service/resolver"
+	"github.com/pritunl/pritunl-client-electron/service/sprofile"
+	"github.com/pritunl/pritunl-client-electron/service/trusted"
+	"github.com/sirupsen/logrus"
+)
+
+// getKillSwitchHosts returns the hosts other than the remotes that the
+// service connects to outside of the tunnel, these are the sync hosts,
+// the proxy servers, the resolvers and the trusted network hosts of the
+// system profiles
+func (d *Data) getKillSwitchHosts() (hosts []string) {
+	hosts = []string{}
+	targets := d.Remotes.GetHosts()
+
+	for _, syncHost := range d.conn.Profile.SyncHosts {
+		u, err := url.Parse(syncHost)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+
+		hosts = append(hosts, u.Hostname())
+		targets = append(targets, u.Host)
+	}
+
+	for _, target := range targets {
+		u, err := proxy.GetUrl(d.conn.Profile.Proxy, target)
+		if err == nil && u != nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+
+	hosts = append(hosts, resolver.GetHosts()...)
+
+	sprfls, err := sprofile.GetAll()
+	if err == nil {
+		for _, sprfl := range sprfls {
+			hosts = append(hosts, trusted.GetHosts(sprfl.TrustedNetworks)...)
+		}
+	}
+
+	return
+}
+
+// updateKillSwitch installs the kill switch with the remotes of the
+// connection and the other hosts used by the service, the kill switch
+// stays active across reconnects until the profile is disconnected by the
+// user or deactivated
+func (d *Data) updateKillSwitch() {
+	if !d.conn.Profile.KillSwitch {
+		return
+	}
+
+	remotes := map[string][]string{}
+	for _, remote := range d.Remotes {
+		remotes[remote.Host] = remote.GetAddrs()
+	}
+
+	for _, host := range d.getKillSwitchHosts() {
+		if _, ok := remotes[host]; ok {
+			continue
+		}
+
+		addrs := []string{}
+		ips, err := resolver.LookupIP(host)
+		if err == nil {
+			for _, ip := range ips {
+				addrs = append(addrs, ip.String())
+			}
+		} else {
+			// DNS is blocked when the kill switch is already active,
+			// keep the stored addresses
+			addrs = killswitch.GetAddrs(d.Id, host)
+			if len(addrs) == 0 {
+				logrus.WithFields(d.conn.Fields(logrus.Fields{
+					"host":  host,
+					"error": err,
+				})).Warn("connection: Failed to resolve kill switch host")
+			}
+		}
+
+		remotes[host] = addrs
+	}
+
+	err := killswitch.Enable(d.Id, remotes, []string{d.Iface})
+	if err != nil {
+		logrus.WithFields(d.conn.Fields(logrus.Fields{
+			"error": err,
+		})).Error("connection: Failed to enable kill switch")
+	}
+}
+
+// lookupKillSwitch adds the addresses stored by the kill switch to the
+// remotes that failed to resolve, DNS is blocked while the kill switch is
+// active without a tunnel
+func (d *Data) lookupKillSwitch(remotes Remotes) {
+	if !d.conn.Profile.KillSwitch || !killswitch.IsActive(d.Id) {
+		return
+	}
+
+	for _, remote := range remotes {
+		if len(remote.GetAddrs()) > 0 {
+			continue
+		}
+
+		for _, addr := range killswitch.GetAddrs(d.Id, remote.Host) {
+			ip := net.ParseIP(addr)
+			if ip != nil {
+				remote.addAddr(ip)
+			}
+		}
+	}
+}
+
+func DisableKillSwitch(prflId string) {
+	err := killswitch.Disable(prflId)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+			"error":      err,
+		}).Error("connection: Failed to disable kill switch")
+	}
+}
+
+// RecoverKillSwitch restores the kill switch of system profiles that will
+// be connected on startup and removes the others
+func RecoverKillSwitch() (err error) {
+	err = sprofile.Reload()
+	if err != nil {
+		return
+	}
+
+	err = killswitch.Recover(func(prflId string) bool {
+		sprfl := sprofile.Get(prflId)
+		return sprfl != nil && sprfl.KillSwitch && sprfl.State
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,134 @@
+package connection
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	AuthSuccess    = "success"
+	AuthDenied     = "denied"
+	AuthOffline    = "offline"
+	AuthSsoTimeout = "sso_timeout"
+	AuthError      = "error"
+)
+
+var GlobalMetrics = &MetricsStore{
+	profiles: map[string]*Metrics{},
+}
+
+type AuthMetrics struct {
+	Remote   string         `json:"remote"`
+	Attempts int            `json:"attempts"`
+	Results  map[string]int `json:"results"`
+}
+
+type Metrics struct {
+	Id             string                  `json:"id"`
+	Reconnects     int                     `json:"reconnects"`
+	PingFailures   int                     `json:"ping_failures"`
+	Authorizations map[string]*AuthMetrics `json:"authorizations"`
+	LastUpdate     time.Time               `json:"last_update"`
+}
+
+type MetricsStore struct {
+	lock     sync.Mutex
+	profiles map[string]*Metrics
+}
+
+func (m *MetricsStore) get(prflId string) (metrics *Metrics) {
+	metrics = m.profiles[prflId]
+	if metrics == nil {
+		metrics = &Metrics{
+			Id:             prflId,
+			Authorizations: map[string]*AuthMetrics{},
+		}
+		m.profiles[prflId] = metrics
+	}
+	metrics.LastUpdate = time.Now()
+
+	return
+}
+
+func (m *MetricsStore) Reconnect(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.get(prflId).Reconnects += 1
+}
+
+func (m *MetricsStore) Remove(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.profiles, prflId)
+}
+
+func (m *MetricsStore) PingFailure(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.get(prflId).PingFailures += 1
+}
+
+func (m *MetricsStore) Authorize(prflId, remote, result string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	metrics := m.get(prflId)
+
+	authMetrics := metrics.Authorizations[remote]
+	if authMetrics == nil {
+		authMetrics = &AuthMetrics{
+			Remote:  remote,
+			Results: map[string]int{},
+		}
+		metrics.Authorizations[remote] = authMetrics
+	}
+
+	authMetrics.Attempts += 1
+	authMetrics.Results[result] += 1
+}
+
+func (m *MetricsStore) GetAll() (profiles map[string]*Metrics) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	profiles = map[string]*Metrics{}
+
+	for prflId, metrics := range m.profiles {
+		authorizations := map[string]*AuthMetrics{}
+		for remote, authMetrics := range metrics.Authorizations {
+			results := map[string]int{}
+			for result, count := range authMetrics.Results {
+				results[result] = count
+			}
+
+			authorizations[remote] = &AuthMetrics{
+				Remote:   authMetrics.Remote,
+				Attempts: authMetrics.Attempts,
+				Results:  results,
+			}
+		}
+
+		profiles[prflId] = &Metrics{
+			Id:             metrics.Id,
+			Reconnects:     metrics.Reconnects,
+			PingFailures:   metrics.PingFailures,
+			Authorizations: authorizations,
+			LastUpdate:     metrics.LastUpdate,
+		}
+	}
+
+	return
+}
@@ -0,0 +1,136 @@
+package connection
+
+import (
+	"testing"
+)
+
+func TestMetricsStore(t *testing.T) {
+	tests := []struct {
+		name           string
+		run            func(m *MetricsStore)
+		reconnects     int
+		pingFailures   int
+		authAttempts   int
+		authResults    map[string]int
+		expectsProfile bool
+	}{
+		{
+			name:           "empty",
+			run:            func(m *MetricsStore) {},
+			expectsProfile: false,
+		},
+		{
+			name: "reconnects",
+			run: func(m *MetricsStore) {
+				m.Reconnect("prfl")
+				m.Reconnect("prfl")
+			},
+			reconnects:     2,
+			authResults:    map[string]int{},
+			expectsProfile: true,
+		},
+		{
+			name: "ping_failures",
+			run: func(m *MetricsStore) {
+				m.PingFailure("prfl")
+			},
+			pingFailures:   1,
+			authResults:    map[string]int{},
+			expectsProfile: true,
+		},
+		{
+			name: "authorizations",
+			run: func(m *MetricsStore) {
+				m.Authorize("prfl", "1.1.1.1", AuthSuccess)
+				m.Authorize("prfl", "1.1.1.1", AuthDenied)
+				m.Authorize("prfl", "1.1.1.1", AuthDenied)
+			},
+			authAttempts: 3,
+			authResults: map[string]int{
+				AuthSuccess: 1,
+				AuthDenied:  2,
+			},
+			expectsProfile: true,
+		},
+		{
+			name: "removed",
+			run: func(m *MetricsStore) {
+				m.Reconnect("prfl")
+				m.Authorize("prfl", "1.1.1.1", AuthError)
+				m.Remove("prfl")
+			},
+			expectsProfile: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &MetricsStore{
+				profiles: map[string]*Metrics{},
+			}
+			test.run(store)
+
+			metrics := store.GetAll()["prfl"]
+			if !test.expectsProfile {
+				if metrics != nil {
+					t.Fatalf("unexpected metrics for profile")
+				}
+				return
+			}
+			if metrics == nil {
+				t.Fatalf("missing metrics for profile")
+			}
+
+			if metrics.Reconnects != test.reconnects {
+				t.Errorf("reconnects %d, expected %d",
+					metrics.Reconnects, test.reconnects)
+			}
+			if metrics.PingFailures != test.pingFailures {
+				t.Errorf("ping failures %d, expected %d",
+					metrics.PingFailures, test.pingFailures)
+			}
+
+			attempts := 0
+			results := map[string]int{}
+			for _, authMetrics := range metrics.Authorizations {
+				attempts += authMetrics.Attempts
+				for result, count := range authMetrics.Results {
+					results[result] += count
+				}
+			}
+			if attempts != test.authAttempts {
+				t.Errorf("auth attempts %d, expected %d",
+					attempts, test.authAttempts)
+			}
+			if len(results) != len(test.authResults) {
+				t.Errorf("auth results %v, expected %v",
+					results, test.authResults)
+			}
+			for result, count := range test.authResults {
+				if results[result] != count {
+					t.Errorf("auth result %s %d, expected %d",
+						result, results[result], count)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsStoreGetAllCopy(t *testing.T) {
+	store := &MetricsStore{
+		profiles: map[string]*Metrics{},
+	}
+	store.Authorize("prfl", "1.1.1.1", AuthSuccess)
+
+	metrics := store.GetAll()
+	metrics["prfl"].Reconnects = 10
+	metrics["prfl"].Authorizations["1.1.1.1"].Results[AuthSuccess] = 10
+
+	metrics = store.GetAll()
+	if metrics["prfl"].Reconnects != 0 {
+		t.Errorf("reconnects modified through copy")
+	}
+	if metrics["prfl"].Authorizations["1.1.1.1"].Results[AuthSuccess] != 1 {
+		t.Errorf("auth results modified through copy")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,8 +27,11 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/command"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
+	"github.com/pritunl/pritunl-client-electron/service/journal"
 	"github.com/pritunl/pritunl-client-electron/service/log"
 	"github.com/pritunl/pritunl-client-electron/service/parser"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
 	"github.com/pritunl/pritunl-client-electron/service/tuntap"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
@@ -49,6 +53,8 @@ type Ovpn struct {
 	authFailed     bool
 	lastAuthFailed time.Time
 	remotes        parser.Remotes
+	pushOpts       []string
+	tunIface       string
 	cmd            *exec.Cmd
 	stdout         io.ReadCloser
 	stderr         io.ReadCloser
@@ -129,11 +135,11 @@ func (o *Ovpn) Connect(data *ConnData) (err error) {
 
 			if remote.Equal(data.Remote) {
 				foundRemote = true
-				remotes = append(remotes, remote)
+				remotes = append(remotes, remote.Restrict(data.Remote))
 			}
 			if remote.Equal(data.Remote6) {
 				foundRemote6 = true
-				remotes = append(remotes, remote)
+				remotes = append(remotes, remote.Restrict(data.Remote6))
 			}
 		}
 
@@ -144,11 +150,11 @@ func (o *Ovpn) Connect(data *ConnData) (err error) {
 
 			if !foundRemote && remote.Equal(data.Remote) {
 				foundRemote = true
-				remotes = append(remotes, remote)
+				remotes = append(remotes, remote.Restrict(data.Remote))
 			}
 			if !foundRemote6 && remote.Equal(data.Remote6) {
 				foundRemote6 = true
-				remotes = append(remotes, remote)
+				remotes = append(remotes, remote.Restrict(data.Remote6))
 			}
 		}
 
@@ -191,6 +197,11 @@ func (o *Ovpn) Connect(data *ConnData) (err error) {
 
 	o.remotes = remotes.GetParser()
 
+	err = o.setProxy()
+	if err != nil {
+		return
+	}
+
 	o.parsedPrfl = parser.Import(
 		o.conn.Profile.Data,
 		o.remotes,
@@ -325,28 +336,7 @@ func (o *Ovpn) Connect(data *ConnData) (err error) {
 		)
 		break
 	case "linux":
-		if HasAppArmor() {
-			logrus.Info("connection: AppArmor enabled DNS support unavailable")
-		} else {
-			upPath, e := o.writeUp()
-			if e != nil {
-				err = e
-				return
-			}
-			o.conn.State.AddPath(upPath)
-
-			downPath, e := o.writeDown()
-			if e != nil {
-				err = e
-				return
-			}
-			o.conn.State.AddPath(downPath)
-
-			args = append(args, "--script-security", "2",
-				"--up", upPath,
-				"--down", downPath,
-			)
-		}
+		args = append(args, "--script-security", "1")
 
 		break
 	default:
@@ -404,6 +394,9 @@ func (o *Ovpn) Connect(data *ConnData) (err error) {
 		return
 	}
 
+	o.conn.State.addJournal(journal.ProcessEntry,
+		strconv.Itoa(o.cmd.Process.Pid), GetOvpnPath())
+
 	o.running = 1
 	go o.watchCmd()
 	go o.waitCmd()
@@ -468,6 +461,116 @@ func (o *Ovpn) write(data *ConnData) (
 	return
 }
 
+// setProxy sets the proxy server for each TCP remote, OpenVPN cannot proxy
+// UDP so UDP remotes that would use a proxy are removed
+func (o *Ovpn) setProxy() (err error) {
+	authPath := ""
+	remotes := parser.Remotes{}
+	skipped := false
+
+	for i := range o.remotes {
+		remote := &o.remotes[i]
+		if !remote.IsTcp() {
+			if proxy.IsActive(o.conn.Profile.Proxy, remote.Host) {
+				logrus.WithFields(o.conn.Fields(logrus.Fields{
+					"remote": remote.Host,
+				})).Error("connection: Proxy not supported for UDP " +
+					"remote, skipping remote")
+				skipped = true
+				continue
+			}
+
+			remotes = append(remotes, *remote)
+			continue
+		}
+
+		srv, e := proxy.GetServer(o.conn.Profile.Proxy, remote.Host)
+		if e != nil {
+			err = e
+			return
+		}
+		if srv == nil {
+			remotes = append(remotes, *remote)
+			continue
+		}
+
+		if srv.Secure {
+			err = &errortypes.ParseError{
+				errors.New("connection: HTTPS proxy not supported " +
+					"by OpenVPN, use an http or socks5 proxy"),
+			}
+			return
+		}
+
+		prxy := &parser.Proxy{
+			Type: srv.Type,
+			Host: srv.Host,
+			Port: srv.Port,
+		}
+
+		if srv.Username != "" {
+			if authPath == "" {
+				authPath, err = o.writeProxyAuth(srv)
+				if err != nil {
+					return
+				}
+				o.conn.State.AddPath(authPath)
+			}
+
+			if runtime.GOOS == "windows" {
+				prxy.AuthPath = strings.ReplaceAll(authPath, "\\", "\\\\")
+			} else {
+				prxy.AuthPath = authPath
+			}
+		}
+
+		remote.Proxy = prxy
+		remotes = append(remotes, *remote)
+	}
+
+	if skipped {
+		o.conn.Data.SendProfileEvent("proxy_remote_skipped")
+	}
+
+	if len(o.remotes) > 0 && len(remotes) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("connection: No remotes available, UDP remotes " +
+				"cannot use a proxy"),
+		}
+		return
+	}
+
+	o.remotes = remotes
+
+	return
+}
+
+func (o *Ovpn) writeProxyAuth(srv *proxy.Server) (pth string, err error) {
+	rootDir, err := GetOvpnConfPath()
+	if err != nil {
+		return
+	}
+
+	if runtime.GOOS == "windows" {
+		pth = filepath.Join(rootDir, o.conn.Id+"-proxy.txt")
+	} else {
+		pth = filepath.Join(rootDir, o.conn.Id+"-proxy")
+	}
+
+	_ = os.Remove(pth)
+	err = ioutil.WriteFile(pth,
+		[]byte(srv.Username+"\n"+srv.Password+"\n"),
+		os.FileMode(0600))
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "profile: Failed to write proxy auth"),
+		}
+		return
+	}
+
+	return
+}
+
 func (o *Ovpn) writeManagementPass() (pth string, err error) {
 	rootDir, err := GetOvpnConfPath()
 	if err != nil {
@@ -677,27 +780,6 @@ func (o *Ovpn) writeUp() (pth string, err error) {
 			script = upScriptDarwin
 		}
 		break
-	case "linux":
-		resolved := true
-
-		resolvData, _ := ioutil.ReadFile("/etc/resolv.conf")
-		if resolvData != nil {
-			resolvDataStr := string(resolvData)
-			if !strings.Contains(resolvDataStr, "systemd-resolved") &&
-				!strings.Contains(resolvDataStr, "127.0.0.53") {
-
-				resolved = false
-			}
-		}
-
-		if o.conn.Profile.DisableDns {
-			script = blockScript
-		} else if resolved {
-			script = resolvedScript
-		} else {
-			script = resolvScript
-		}
-		break
 	default:
 		panic("profile: Not implemented")
 	}
@@ -735,27 +817,6 @@ func (o *Ovpn) writeDown() (pth string, err error) {
 			script = downScriptDarwin
 		}
 		break
-	case "linux":
-		resolved := true
-
-		resolvData, _ := ioutil.ReadFile("/etc/resolv.conf")
-		if resolvData != nil {
-			resolvDataStr := string(resolvData)
-			if !strings.Contains(resolvDataStr, "systemd-resolved") &&
-				!strings.Contains(resolvDataStr, "127.0.0.53") {
-
-				resolved = false
-			}
-		}
-
-		if o.conn.Profile.DisableDns {
-			script = blockScript
-		} else if resolved {
-			script = resolvedScript
-		} else {
-			script = resolvScript
-		}
-		break
 	default:
 		panic("profile: Not implemented")
 	}
@@ -1001,13 +1062,30 @@ func (o *Ovpn) parseLine(line string) {
 	}
 
 	if strings.Contains(line, "Initialization Sequence Completed") {
+		if !o.conn.Data.SetStatus(Connected, "ovpn_initialized") {
+			return
+		}
 		o.connected = true
-		o.conn.Data.Status = Connected
 		o.conn.Data.Timestamp = time.Now().Unix() - 3
 		o.conn.Data.UpdateEvent()
 
 		o.conn.Data.ValidateAuthToken()
 
+		opts := strings.Split(o.parsedPrfl.Export(""), "\n")
+		opts = append(opts, o.pushOpts...)
+
+		servers, domains := parseOvpnDns(opts)
+		o.conn.Data.DnsServers = servers
+		o.conn.Data.SearchDomains = domains
+		o.conn.Data.Routes, o.conn.Data.Routes6 = parseOvpnRouteList(opts)
+
+		if runtime.GOOS == "linux" {
+			o.conn.Data.setDns(o.tunIface, servers, domains)
+		}
+		o.conn.Data.updateIpv6Guard(parseOvpnRoutes(opts))
+
+		o.conn.Data.runHook(hooks.Connected)
+
 		go func() {
 			defer func() {
 				panc := recover()
@@ -1021,6 +1099,10 @@ func (o *Ovpn) parseLine(line string) {
 
 			utils.ClearDNSCache()
 		}()
+	} else if strings.Contains(line, "PUSH_REPLY,") {
+		o.pushOpts = parseOvpnPush(line)
+	} else if match := ovpnTunReg.FindStringSubmatch(line); match != nil {
+		o.tunIface = match[1]
 	} else if strings.Contains(line, "Inactivity timeout (--inactive)") {
 		o.conn.Data.SendProfileEvent("inactive")
 	} else if strings.Contains(line, "Inactivity timeout") ||
@@ -1057,7 +1139,7 @@ func (o *Ovpn) parseLine(line string) {
 
 		o.authFailed = true
 		o.conn.Data.ResetAuthToken()
-		o.conn.State.NoReconnect("ovpn_auth_error")
+		o.conn.State.Failure(AuthFailure, "ovpn_auth_error")
 		o.conn.State.SetStop()
 
 		if o.conn.Profile.SystemProfile {
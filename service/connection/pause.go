@@ -0,0 +1,183 @@
+package connection
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var GlobalPause = &PauseStore{
+	states: map[string]*Pause{},
+}
+
+type Pause struct {
+	Start      time.Time `json:"start"`
+	Until      time.Time `json:"until"`
+	profile    *Profile
+	generation int
+}
+
+type PauseStore struct {
+	lock       sync.Mutex
+	generation int
+	states     map[string]*Pause
+}
+
+func (p *PauseStore) IsPaused(prflId string) bool {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.states[prflId] != nil
+}
+
+func (p *PauseStore) isCurrent(prflId string, generation int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	state := p.states[prflId]
+	return state != nil && state.generation == generation
+}
+
+// Pause stops the connection and keeps the profile and auth token, the
+// connection is started again after the duration, a zero duration pauses
+// until resumed
+func (p *PauseStore) Pause(conn *Connection, duration time.Duration) {
+	p.lock.Lock()
+	p.generation += 1
+	state := &Pause{
+		Start:      time.Now(),
+		profile:    conn.Profile,
+		generation: p.generation,
+	}
+	if duration > 0 {
+		state.Until = state.Start.Add(duration)
+	}
+	p.states[conn.Id] = state
+	p.lock.Unlock()
+
+	logrus.WithFields(conn.Fields(logrus.Fields{
+		"pause_duration": duration.String(),
+	})).Info("profile: Pausing connection")
+
+	GlobalBackoff.Reset(conn.Id)
+	DisableKillSwitch(conn.Id)
+	conn.State.NoReconnect("pause")
+	conn.StopBackground()
+
+	if duration > 0 {
+		go p.wait(conn.Id, state.generation, duration)
+	}
+}
+
+func (p *PauseStore) wait(prflId string, generation int,
+	duration time.Duration) {
+
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"profile_id": prflId,
+				"trace":      string(debug.Stack()),
+				"panic":      panc,
+			}).Error("profile: Pause wait panic")
+		}
+	}()
+
+	start := time.Now()
+	for time.Since(start) < duration {
+		time.Sleep(250 * time.Millisecond)
+
+		if Shutdown || !p.isCurrent(prflId, generation) {
+			return
+		}
+	}
+
+	if !p.isCurrent(prflId, generation) {
+		return
+	}
+
+	p.Resume(prflId)
+}
+
+// Resume removes the pause and starts the connection, system profiles are
+// started by the system profile sync
+func (p *PauseStore) Resume(prflId string) (resumed bool) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	p.lock.Lock()
+	state := p.states[prflId]
+	delete(p.states, prflId)
+	p.lock.Unlock()
+
+	if state == nil {
+		return
+	}
+	resumed = true
+
+	logrus.WithFields(logrus.Fields{
+		"profile_id": prflId,
+	}).Info("profile: Resuming connection")
+
+	if state.profile.SystemProfile {
+		return
+	}
+
+	conn, err := NewConnection(state.profile)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+			"error":      err,
+		}).Error("profile: Failed to init connection in resume")
+		return
+	}
+
+	go func() {
+		defer func() {
+			panc := recover()
+			if panc != nil {
+				logrus.WithFields(logrus.Fields{
+					"trace": string(debug.Stack()),
+					"panic": panc,
+				}).Error("profile: Resume start panic")
+			}
+		}()
+
+		err := conn.Start(Options{})
+		if err != nil {
+			logrus.WithFields(conn.Fields(logrus.Fields{
+				"error": err,
+			})).Error("profile: Failed to start connection in resume")
+			return
+		}
+	}()
+
+	return
+}
+
+// Clear removes the pause without starting the connection
+func (p *PauseStore) Clear(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.states, prflId)
+}
+
+func (p *PauseStore) GetAll() (states map[string]*Pause) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	states = map[string]*Pause{}
+	for prflId, state := range p.states {
+		stateCopy := *state
+		states[prflId] = &stateCopy
+	}
+
+	return
+}
@@ -37,6 +37,11 @@ type Profile struct {
 	RegistrationKey    string                      `json:"registration_key"`
 	TokenTtl           int                         `json:"token_ttl"`
 	Reconnect          bool                        `json:"reconnect"`
+	ReconnectPolicy    *types.ReconnectPolicy      `json:"reconnect_policy"`
+	Proxy              *types.Proxy                `json:"proxy"`
+	TlsPins            []string                    `json:"tls_pins"`
+	KillSwitch         bool                        `json:"kill_switch"`
+	Ipv6Guard          bool                        `json:"ipv6_guard"`
 	Timeout            bool                        `json:"timeout"`
 	SystemProfile      bool                        `json:"-"`
 }
@@ -60,7 +65,16 @@ func (p *Profile) Fields() logrus.Fields {
 }
 
 func (p *Profile) IsGeoSort() bool {
-	return p.GeoSort != ""
+	return p.GeoSort != "" && p.GeoSort != LatencySort &&
+		p.GeoSort != HealthSort
+}
+
+func (p *Profile) IsLatencySort() bool {
+	return p.GeoSort == LatencySort
+}
+
+func (p *Profile) IsHealthSort() bool {
+	return p.GeoSort == HealthSort
 }
 
 func (p *Profile) Sync() {
@@ -129,5 +143,10 @@ func (p *Profile) ImportSystemProfile(sprfl *sprofile.Sprofile) {
 	p.RegistrationKey = sprfl.RegistrationKey
 	p.TokenTtl = sprfl.TokenTtl
 	p.Reconnect = true
+	p.ReconnectPolicy = sprfl.ReconnectPolicy.Copy()
+	p.Proxy = sprfl.Proxy.Copy()
+	p.TlsPins = sprfl.TlsPins
+	p.KillSwitch = sprfl.KillSwitch
+	p.Ipv6Guard = sprfl.Ipv6Guard
 	p.SystemProfile = true
 }
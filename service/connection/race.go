@@ -0,0 +1,154 @@
+package connection
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultRaceDelay = 1000
+
+type raceResult struct {
+	host  string
+	start time.Time
+	data  *ConnData
+	evt   *event.Event
+	err   error
+}
+
+func getRaceDelay() time.Duration {
+	if config.Config.RemoteRaceDelay > 0 {
+		return time.Duration(config.Config.RemoteRaceDelay) *
+			time.Millisecond
+	}
+	return defaultRaceDelay * time.Millisecond
+}
+
+// isRaceRemotes returns true if the remotes should be raced, single sign-on
+// profiles are excluded to avoid opening a sign-on session for each remote
+func (c *Client) isRaceRemotes() bool {
+	return config.Config.RemoteRace && !c.conn.Profile.SsoAuth &&
+		len(c.conn.Data.Remotes) > 1
+}
+
+func (c *Client) raceRemote(ctx *utils.CancelContext, remote *Remote,
+	results chan *raceResult) {
+
+	result := &raceResult{
+		host:  remote.Host,
+		start: time.Now(),
+	}
+
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(c.conn.Fields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			})).Error("profile: Race remote panic")
+		}
+		results <- result
+	}()
+
+	result.data, _, result.evt, result.err = c.authorizeRemote(
+		ctx, remote)
+}
+
+// raceRemotes starts an authorization request for each remote in priority
+// order with a staggered delay, the first successful response is used and
+// the remaining requests are cancelled. A failed request starts the next
+// remote without waiting for the delay.
+func (c *Client) raceRemotes() (data *ConnData, evt *event.Event,
+	connErrors []ConnectionError) {
+
+	remotes := c.conn.Data.Remotes
+	delay := getRaceDelay()
+	results := make(chan *raceResult, len(remotes))
+	ctxs := []*utils.CancelContext{}
+
+	defer func() {
+		for _, ctx := range ctxs {
+			ctx.Cancel()
+		}
+	}()
+
+	logrus.WithFields(c.conn.Fields(logrus.Fields{
+		"remotes":    remotes.GetFormatted(),
+		"race_delay": delay.String(),
+	})).Info("connection: Racing remotes")
+
+	started := 0
+	finished := 0
+	startNext := func() {
+		remote := remotes[started]
+		started += 1
+
+		logrus.WithFields(c.conn.Fields(logrus.Fields{
+			"remote": remote.GetFormatted(),
+		})).Info("connection: Attempting remote")
+
+		ctx := c.GetContext()
+		ctxs = append(ctxs, ctx)
+		go c.raceRemote(ctx, remote, results)
+	}
+
+	startNext()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for finished < started {
+		if c.conn.State.IsStop() {
+			return
+		}
+
+		var nextStart <-chan time.Time
+		if started < len(remotes) {
+			timer.Reset(delay)
+			nextStart = timer.C
+		}
+
+		select {
+		case result := <-results:
+			finished += 1
+
+			authResult := getAuthResult(
+				result.data, result.evt, result.err)
+			GlobalMetrics.Authorize(c.conn.Id, result.host, authResult)
+			c.recordAuthorize(result.host, authResult, result.start)
+
+			if result.err == nil && result.data != nil {
+				logrus.WithFields(c.conn.Fields(logrus.Fields{
+					"remote": result.host,
+				})).Info("connection: Remote race won")
+
+				c.remote = result.host
+				data = result.data
+				connErrors = nil
+				return
+			}
+
+			if result.err != nil {
+				connErrors = append(connErrors, ConnectionError{
+					Host:  result.host,
+					Error: result.err,
+				})
+			}
+			if result.evt != nil {
+				evt = result.evt
+			}
+
+			if started < len(remotes) {
+				startNext()
+			}
+		case <-nextStart:
+			startNext()
+		}
+	}
+
+	return
+}
@@ -0,0 +1,266 @@
+package connection
+
+import (
+	"math"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/types"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	NetworkFailure      = "network"
+	AuthFailure         = "auth"
+	RegistrationFailure = "registration"
+	VerificationFailure = "verification"
+
+	defaultInitialDelay = 1
+	defaultMaxDelay     = 300
+	defaultMultiplier   = 2.0
+	defaultJitter       = 0.2
+	defaultResetAfter   = 120
+)
+
+var GlobalBackoff = &BackoffStore{
+	states: map[string]*Backoff{},
+}
+
+type Backoff struct {
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	Delay       float64   `json:"delay"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Failure     string    `json:"failure"`
+	Pending     bool      `json:"pending"`
+	Exhausted   bool      `json:"exhausted"`
+	connected   time.Time
+	generation  int
+}
+
+type BackoffStore struct {
+	lock       sync.Mutex
+	generation int
+	states     map[string]*Backoff
+}
+
+// HasReconnectPolicy returns true if a reconnect policy is set on the
+// profile or in the global config
+func (p *Profile) HasReconnectPolicy() bool {
+	return p.ReconnectPolicy != nil || config.Config.ReconnectPolicy != nil
+}
+
+// GetReconnectPolicy returns the profile policy or the global policy with
+// unset values replaced by the defaults
+func (p *Profile) GetReconnectPolicy() (policy *types.ReconnectPolicy) {
+	if p.ReconnectPolicy != nil {
+		policy = p.ReconnectPolicy.Copy()
+	} else if config.Config.ReconnectPolicy != nil {
+		policy = config.Config.ReconnectPolicy.Copy()
+	} else {
+		policy = &types.ReconnectPolicy{}
+	}
+
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = defaultInitialDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = defaultMaxDelay
+	}
+	if policy.MaxDelay < policy.InitialDelay {
+		policy.MaxDelay = policy.InitialDelay
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaultMultiplier
+	}
+	if policy.Jitter == nil || *policy.Jitter < 0 || *policy.Jitter > 1 {
+		jitter := defaultJitter
+		policy.Jitter = &jitter
+	}
+	if policy.ResetAfter <= 0 {
+		policy.ResetAfter = defaultResetAfter
+	}
+	if policy.Retry == nil {
+		policy.Retry = []string{
+			NetworkFailure,
+		}
+	}
+
+	return
+}
+
+func IsRetryable(policy *types.ReconnectPolicy, failure string) bool {
+	for _, retry := range policy.Retry {
+		if retry == failure {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *BackoffStore) get(prflId string) (state *Backoff) {
+	state = b.states[prflId]
+	if state == nil {
+		state = &Backoff{}
+		b.states[prflId] = state
+	}
+
+	return
+}
+
+// Next registers a reconnect attempt and returns the delay before the
+// attempt should start, ok is false once the attempts are exhausted. If
+// immediate is set the first attempt starts without a delay and the
+// backoff starts with the second attempt.
+func (b *BackoffStore) Next(prflId string, policy *types.ReconnectPolicy,
+	failure string, immediate bool) (delay time.Duration, generation int,
+	ok bool) {
+
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.get(prflId)
+
+	if !state.connected.IsZero() && time.Since(state.connected) >=
+		time.Duration(policy.ResetAfter)*time.Second {
+
+		state.Attempts = 0
+	}
+	state.connected = time.Time{}
+	state.MaxAttempts = policy.MaxAttempts
+	state.Failure = failure
+
+	if policy.MaxAttempts > 0 && state.Attempts >= policy.MaxAttempts {
+		state.Pending = false
+		state.Exhausted = true
+		state.Delay = 0
+		state.NextAttempt = time.Time{}
+		return
+	}
+
+	exponent := state.Attempts
+	if immediate {
+		exponent -= 1
+	}
+
+	delaySec := 0.0
+	if exponent >= 0 {
+		delaySec = float64(policy.InitialDelay) * math.Pow(
+			policy.Multiplier, float64(exponent))
+		if delaySec > float64(policy.MaxDelay) {
+			delaySec = float64(policy.MaxDelay)
+		}
+		delaySec *= 1 + *policy.Jitter*(mathrand.Float64()*2-1)
+		if delaySec < 0 {
+			delaySec = 0
+		}
+	}
+
+	delay = time.Duration(delaySec * float64(time.Second))
+
+	b.generation += 1
+	generation = b.generation
+
+	state.Attempts += 1
+	state.Delay = math.Round(delaySec*100) / 100
+	state.NextAttempt = time.Now().Add(delay)
+	state.Pending = true
+	state.Exhausted = false
+	state.generation = generation
+	ok = true
+
+	return
+}
+
+// Start marks the pending attempt as started, returns false if the
+// attempt was cancelled by a reset or a newer attempt
+func (b *BackoffStore) Start(prflId string, generation int) bool {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.states[prflId]
+	if state == nil || !state.Pending || state.generation != generation {
+		return false
+	}
+	state.Pending = false
+
+	return true
+}
+
+func (b *BackoffStore) IsCurrent(prflId string, generation int) bool {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.states[prflId]
+	return state != nil && state.Pending && state.generation == generation
+}
+
+// IsBlocked returns true if a reconnect is pending or the reconnect
+// attempts have been exhausted
+func (b *BackoffStore) IsBlocked(prflId string) bool {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.states[prflId]
+	return state != nil && (state.Pending || state.Exhausted)
+}
+
+func (b *BackoffStore) Connected(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state := b.states[prflId]
+	if state != nil {
+		state.connected = time.Now()
+	}
+}
+
+func (b *BackoffStore) Reset(prflId string) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.states, prflId)
+}
+
+func (b *BackoffStore) Get(prflId string) (state *Backoff) {
+	prflId = utils.FilterStrN(prflId, 128)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	curState := b.states[prflId]
+	if curState != nil {
+		stateCopy := *curState
+		state = &stateCopy
+	}
+
+	return
+}
+
+func (b *BackoffStore) GetAll() (states map[string]*Backoff) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	states = map[string]*Backoff{}
+	for prflId, state := range b.states {
+		stateCopy := *state
+		states[prflId] = &stateCopy
+	}
+
+	return
+}
@@ -0,0 +1,190 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/types"
+)
+
+func testPolicy(maxAttempts int, jitter float64) *types.ReconnectPolicy {
+	return &types.ReconnectPolicy{
+		MaxAttempts:  maxAttempts,
+		InitialDelay: 1,
+		MaxDelay:     10,
+		Multiplier:   2,
+		Jitter:       &jitter,
+		ResetAfter:   60,
+		Retry:        []string{NetworkFailure},
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		immediate   bool
+		delays      []time.Duration
+		exhausted   bool
+	}{
+		{
+			name:      "immediate",
+			immediate: true,
+			delays: []time.Duration{
+				0,
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+			},
+		},
+		{
+			name:      "policy",
+			immediate: false,
+			delays: []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				8 * time.Second,
+			},
+		},
+		{
+			name:      "max_delay",
+			immediate: false,
+			delays: []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				8 * time.Second,
+				10 * time.Second,
+				10 * time.Second,
+			},
+		},
+		{
+			name:        "max_attempts",
+			maxAttempts: 2,
+			immediate:   true,
+			delays: []time.Duration{
+				0,
+				1 * time.Second,
+			},
+			exhausted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &BackoffStore{
+				states: map[string]*Backoff{},
+			}
+			policy := testPolicy(test.maxAttempts, 0)
+
+			for i, expected := range test.delays {
+				delay, _, ok := store.Next(
+					"prfl", policy, NetworkFailure, test.immediate)
+				if !ok {
+					t.Fatalf("attempt %d exhausted", i)
+				}
+				if delay != expected {
+					t.Fatalf("attempt %d delay %s, expected %s",
+						i, delay, expected)
+				}
+			}
+
+			_, _, ok := store.Next(
+				"prfl", policy, NetworkFailure, test.immediate)
+			if ok == test.exhausted {
+				t.Fatalf("next ok %t, expected %t", ok, !test.exhausted)
+			}
+
+			state := store.Get("prfl")
+			if state.Exhausted != test.exhausted {
+				t.Errorf("exhausted %t, expected %t",
+					state.Exhausted, test.exhausted)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	store := &BackoffStore{
+		states: map[string]*Backoff{},
+	}
+	policy := testPolicy(0, 0.5)
+
+	for i := 0; i < 100; i++ {
+		store.Reset("prfl")
+
+		delay, _, ok := store.Next("prfl", policy, NetworkFailure, false)
+		if !ok {
+			t.Fatalf("attempt exhausted")
+		}
+		if delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Fatalf("delay %s outside of jitter range", delay)
+		}
+	}
+}
+
+func TestBackoffResetAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		connected time.Duration
+		expected  time.Duration
+	}{
+		{
+			name:      "short_connection",
+			connected: 10 * time.Second,
+			expected:  2 * time.Second,
+		},
+		{
+			name:      "stable_connection",
+			connected: 2 * time.Minute,
+			expected:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &BackoffStore{
+				states: map[string]*Backoff{},
+			}
+			policy := testPolicy(0, 0)
+
+			store.Next("prfl", policy, NetworkFailure, true)
+			store.Next("prfl", policy, NetworkFailure, true)
+
+			store.Connected("prfl")
+			store.states["prfl"].connected = time.Now().Add(-test.connected)
+
+			delay, _, _ := store.Next("prfl", policy, NetworkFailure, true)
+			if delay != test.expected {
+				t.Errorf("delay %s, expected %s", delay, test.expected)
+			}
+		})
+	}
+}
+
+func TestBackoffGeneration(t *testing.T) {
+	store := &BackoffStore{
+		states: map[string]*Backoff{},
+	}
+	policy := testPolicy(0, 0)
+
+	_, first, _ := store.Next("prfl", policy, NetworkFailure, true)
+	_, second, _ := store.Next("prfl", policy, NetworkFailure, true)
+
+	if store.Start("prfl", first) {
+		t.Errorf("started superseded attempt")
+	}
+	if !store.IsBlocked("prfl") {
+		t.Errorf("pending attempt not blocked")
+	}
+	if !store.Start("prfl", second) {
+		t.Errorf("failed to start current attempt")
+	}
+	if store.Start("prfl", second) {
+		t.Errorf("started attempt twice")
+	}
+	if store.IsBlocked("prfl") {
+		t.Errorf("started attempt still blocked")
+	}
+}
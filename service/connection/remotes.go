@@ -4,18 +4,27 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/health"
 	"github.com/pritunl/pritunl-client-electron/service/parser"
+	"github.com/pritunl/pritunl-client-electron/service/resolver"
+	"github.com/pritunl/pritunl-client-electron/service/types"
 	"github.com/sirupsen/logrus"
 )
 
+// Remote stores the first resolved address of each family in Addr4 and
+// Addr6, all resolved addresses are stored in Addrs4 and Addrs6
 type Remote struct {
 	Host      string
 	Addr4     string
 	Addr6     string
+	Addrs4    []string
+	Addrs6    []string
 	OvpnPort  int
 	OvpnProto string
 	Type      string
@@ -37,12 +46,7 @@ func (r Remotes) GetAddrs() (addrs []string) {
 	addrs = []string{}
 
 	for _, remote := range r {
-		if remote.Addr4 != "" {
-			addrs = append(addrs, remote.Addr4)
-		}
-		if remote.Addr6 != "" {
-			addrs = append(addrs, remote.Addr6)
-		}
+		addrs = append(addrs, remote.GetAddrs()...)
 	}
 
 	return
@@ -63,14 +67,14 @@ func (r Remotes) GetAddrMap() (addrMap map[string]*Remote, other []*Remote) {
 	other = []*Remote{}
 
 	for _, remote := range r {
-		if remote.Addr4 != "" {
-			addrMap[remote.Addr4] = remote
-		}
-		if remote.Addr6 != "" {
-			addrMap[remote.Addr6] = remote
+		addrs := remote.GetAddrs()
+		for _, addr := range addrs {
+			if addrMap[addr] == nil {
+				addrMap[addr] = remote
+			}
 		}
 
-		if remote.Addr4 == "" && remote.Addr6 == "" {
+		if len(addrs) == 0 {
 			other = append(other, remote)
 		}
 	}
@@ -88,17 +92,95 @@ func (r Remotes) GetParser() (remotes parser.Remotes) {
 	return
 }
 
-func (r *Remote) Lookup() {
-	ip := net.ParseIP(r.Host)
-	if ip != nil {
-		ipStr := ip.String()
-		if ip.To4() == nil {
-			r.Addr6 = ipStr
+// SortHealth orders the remotes by the stored health score, remotes with
+// equal scores keep the current order
+func (r Remotes) SortHealth(prflId string) (remotes Remotes) {
+	scores := health.Scores(prflId)
+	remotes = append(Remotes{}, r...)
+
+	getScore := func(remote *Remote) float64 {
+		score, ok := scores[remote.Host]
+		if !ok {
+			return health.NeutralScore
+		}
+		return score
+	}
+
+	sort.SliceStable(remotes, func(i, j int) bool {
+		return getScore(remotes[i]) > getScore(remotes[j])
+	})
+
+	return
+}
+
+// DemoteFailing moves remotes that recently failed to the end and keeps
+// the current order otherwise
+func (r Remotes) DemoteFailing(prflId string) (remotes Remotes) {
+	remotes = Remotes{}
+	failing := Remotes{}
+
+	for _, remote := range r {
+		if health.IsFailing(prflId, remote.Host) {
+			failing = append(failing, remote)
 		} else {
+			remotes = append(remotes, remote)
+		}
+	}
+
+	remotes = append(remotes, failing...)
+
+	return
+}
+
+// Lookup resolves all remotes in parallel
+func (r Remotes) Lookup() {
+	waiter := sync.WaitGroup{}
+
+	for _, remote := range r {
+		waiter.Add(1)
+		go func(remote *Remote) {
+			defer waiter.Done()
+			remote.Lookup()
+		}(remote)
+	}
+
+	waiter.Wait()
+}
+
+func (r *Remote) addAddr(ip net.IP) {
+	ipStr := ip.String()
+
+	if ip.To4() == nil {
+		for _, addr := range r.Addrs6 {
+			if addr == ipStr {
+				return
+			}
+		}
+
+		if r.Addr6 == "" {
+			r.Addr6 = ipStr
+		}
+		r.Addrs6 = append(r.Addrs6, ipStr)
+	} else {
+		for _, addr := range r.Addrs4 {
+			if addr == ipStr {
+				return
+			}
+		}
+
+		if r.Addr4 == "" {
 			r.Addr4 = ipStr
 		}
+		r.Addrs4 = append(r.Addrs4, ipStr)
+	}
+}
+
+func (r *Remote) Lookup() {
+	ip := net.ParseIP(r.Host)
+	if ip != nil {
+		r.addAddr(ip)
 	} else {
-		remoteIps, err := net.LookupIP(r.Host)
+		remoteIps, err := resolver.LookupIP(r.Host)
 		if err != nil {
 			err = &errortypes.RequestError{
 				errors.Wrap(err, "remotes: Failed to resolve remote"),
@@ -112,24 +194,43 @@ func (r *Remote) Lookup() {
 		}
 
 		for _, remoteIp := range remoteIps {
-			remoteIpStr := remoteIp.String()
-			if remoteIp.To4() == nil {
-				if r.Addr6 == "" {
-					r.Addr6 = remoteIpStr
-				} else {
-					// TODO Handle multiple
-				}
-			} else {
-				if r.Addr4 == "" {
-					r.Addr4 = remoteIpStr
-				} else {
-					// TODO Handle multiple
-				}
-			}
+			r.addAddr(remoteIp)
 		}
 	}
 }
 
+func (r *Remote) GetAddrs4() (addrs []string) {
+	if len(r.Addrs4) > 0 {
+		addrs = r.Addrs4
+	} else if r.Addr4 != "" {
+		addrs = []string{r.Addr4}
+	} else {
+		addrs = []string{}
+	}
+
+	return
+}
+
+func (r *Remote) GetAddrs6() (addrs []string) {
+	if len(r.Addrs6) > 0 {
+		addrs = r.Addrs6
+	} else if r.Addr6 != "" {
+		addrs = []string{r.Addr6}
+	} else {
+		addrs = []string{}
+	}
+
+	return
+}
+
+// GetAddrs returns all resolved addresses with IPv4 addresses first
+func (r *Remote) GetAddrs() (addrs []string) {
+	addrs = append([]string{}, r.GetAddrs4()...)
+	addrs = append(addrs, r.GetAddrs6()...)
+
+	return
+}
+
 func (r *Remote) Equal(addr string) bool {
 	if strings.Contains(addr, ":") {
 		var hostIp6 net.IP
@@ -137,26 +238,64 @@ func (r *Remote) Equal(addr string) bool {
 			hostIp6 = net.ParseIP(r.Host)
 		}
 
-		var addrIp6 net.IP
-		if strings.Contains(r.Addr6, ":") {
-			addrIp6 = net.ParseIP(r.Addr6)
-		}
-
 		ip6 := net.ParseIP(addr)
 		if ip6 != nil {
-			if ip6.Equal(hostIp6) || ip6.Equal(addrIp6) {
+			if ip6.Equal(hostIp6) {
 				return true
 			}
+
+			for _, remoteAddr6 := range r.GetAddrs6() {
+				if ip6.Equal(net.ParseIP(remoteAddr6)) {
+					return true
+				}
+			}
 		}
 	}
 
-	if addr == r.Host || addr == r.Addr4 || addr == r.Addr6 {
+	if addr == r.Host {
 		return true
 	}
 
+	for _, remoteAddr := range r.GetAddrs() {
+		if addr == remoteAddr {
+			return true
+		}
+	}
+
 	return false
 }
 
+// Restrict returns a copy of the remote limited to the address when the
+// address is one of the resolved addresses
+func (r *Remote) Restrict(addr string) (remote *Remote) {
+	if addr == "" || addr == r.Host {
+		remote = r
+		return
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		remote = r
+		return
+	}
+
+	for _, remoteAddr := range r.GetAddrs() {
+		if ip.Equal(net.ParseIP(remoteAddr)) {
+			remote = &Remote{
+				Host:      r.Host,
+				OvpnPort:  r.OvpnPort,
+				OvpnProto: r.OvpnProto,
+				Type:      r.Type,
+			}
+			remote.addAddr(ip)
+			return
+		}
+	}
+
+	remote = r
+	return
+}
+
 func (r *Remote) GetUrl(path string) *url.URL {
 	remote := r.Host
 
@@ -177,11 +316,8 @@ func (r *Remote) GetFormatted() (host string) {
 	if r.Type == SyncRemote {
 		host += "*"
 	}
-	if r.Addr4 != "" {
-		host += fmt.Sprintf("[%s]", r.Addr4)
-	}
-	if r.Addr6 != "" {
-		host += fmt.Sprintf("[%s]", r.Addr6)
+	for _, addr := range r.GetAddrs() {
+		host += fmt.Sprintf("[%s]", addr)
 	}
 
 	return
@@ -190,22 +326,16 @@ func (r *Remote) GetFormatted() (host string) {
 func (r *Remote) GetParser() (remotes parser.Remotes) {
 	remotes = parser.Remotes{}
 
-	if r.Addr4 != "" {
+	addrs := r.GetAddrs()
+	for _, addr := range addrs {
 		remotes = append(remotes, parser.Remote{
-			Host:  r.Addr4,
-			Port:  r.OvpnPort,
-			Proto: r.OvpnProto,
-		})
-	}
-	if r.Addr6 != "" {
-		remotes = append(remotes, parser.Remote{
-			Host:  r.Addr6,
+			Host:  addr,
 			Port:  r.OvpnPort,
 			Proto: r.OvpnProto,
 		})
 	}
 
-	if r.Addr4 == "" && r.Addr6 == "" && r.Host != "" {
+	if len(addrs) == 0 && r.Host != "" {
 		remotes = append(remotes, parser.Remote{
 			Host:  r.Host,
 			Port:  r.OvpnPort,
@@ -215,3 +345,72 @@ func (r *Remote) GetParser() (remotes parser.Remotes) {
 
 	return
 }
+
+type remoteTier struct {
+	priority    int
+	remotes     Remotes
+	syncRemotes Remotes
+}
+
+// getRemotePriority returns the priority of the remote from the profile
+// remotes data, remotes without data have a priority of zero
+func getRemotePriority(remotesData map[string]types.RemoteData,
+	host string) int {
+
+	if remotesData == nil {
+		return 0
+	}
+
+	data, ok := remotesData[host]
+	if ok {
+		return data.Priority
+	}
+
+	hostname, _, err := net.SplitHostPort(host)
+	if err == nil {
+		data, ok = remotesData[strings.Trim(hostname, "[]")]
+		if ok {
+			return data.Priority
+		}
+	}
+
+	return 0
+}
+
+// groupPriority groups the remotes into tiers by priority, higher priority
+// tiers are ordered first
+func groupPriority(remotesData map[string]types.RemoteData,
+	remotes, syncRemotes Remotes) (tiers []*remoteTier) {
+
+	tierMap := map[int]*remoteTier{}
+	tiers = []*remoteTier{}
+
+	getTier := func(priority int) *remoteTier {
+		tier := tierMap[priority]
+		if tier == nil {
+			tier = &remoteTier{
+				priority:    priority,
+				remotes:     Remotes{},
+				syncRemotes: Remotes{},
+			}
+			tierMap[priority] = tier
+			tiers = append(tiers, tier)
+		}
+		return tier
+	}
+
+	for _, remote := range remotes {
+		tier := getTier(getRemotePriority(remotesData, remote.Host))
+		tier.remotes = append(tier.remotes, remote)
+	}
+	for _, remote := range syncRemotes {
+		tier := getTier(getRemotePriority(remotesData, remote.Host))
+		tier.syncRemotes = append(tier.syncRemotes, remote)
+	}
+
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].priority > tiers[j].priority
+	})
+
+	return
+}
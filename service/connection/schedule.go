@@ -0,0 +1,50 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/schedule"
+	"github.com/pritunl/pritunl-client-electron/service/sprofile"
+	"github.com/pritunl/pritunl-client-electron/service/trusted"
+	"github.com/sirupsen/logrus"
+)
+
+type ScheduleEvent struct {
+	Id   string    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+// getSystemState returns true if the system profile should be connected,
+// the schedule replaces the profile state unless the schedule has been
+// overridden for the current day, scheduled profiles are not connected on
+// a trusted network
+func getSystemState(sPrfl *sprofile.Sprofile, conn *Connection) bool {
+	if !schedule.IsSet(sPrfl.Schedule) || schedule.IsOverride(sPrfl.Id) {
+		return sPrfl.State
+	}
+
+	active, end := schedule.Window(sPrfl.Schedule, time.Now())
+	if active && trusted.IsTrusted(sPrfl.Id) {
+		return false
+	}
+
+	if active && conn != nil &&
+		schedule.ShouldWarn(sPrfl.Id, sPrfl.Schedule, end) {
+
+		logrus.WithFields(conn.Fields(logrus.Fields{
+			"disconnect_time": end,
+		})).Info("profile: Scheduled disconnect pending")
+
+		evt := &event.Event{
+			Type: "schedule_disconnect",
+			Data: &ScheduleEvent{
+				Id:   sPrfl.Id,
+				Time: end,
+			},
+		}
+		evt.Init()
+	}
+
+	return active
+}
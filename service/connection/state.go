@@ -5,6 +5,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
+	"github.com/pritunl/pritunl-client-electron/service/journal"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +22,8 @@ type State struct {
 	delay              bool
 	interactive        bool
 	noReconnect        bool
+	noReconnectReason  string
+	failure            string
 	closed             bool
 	systemInteractive  bool
 	closeWaiters       []chan bool
@@ -35,6 +39,7 @@ func (s *State) Fields() logrus.Fields {
 		"state_deadline":           s.deadline,
 		"state_delay":              s.delay,
 		"state_no_reconnect":       s.noReconnect,
+		"state_failure":            s.failure,
 		"state_interactive":        s.interactive,
 		"state_system_interactive": s.systemInteractive,
 		"state_closed":             s.closed,
@@ -85,6 +90,24 @@ func (s *State) NoReconnect(reason string) {
 		"reason": reason,
 	})).Info("connection: Stopping reconnect")
 	s.noReconnect = true
+	s.noReconnectReason = reason
+}
+
+// Failure sets the failure class of the connection, reconnect is stopped
+// if the reconnect policy does not retry the failure class
+func (s *State) Failure(failure, reason string) {
+	s.failure = failure
+
+	if !IsRetryable(s.conn.Profile.GetReconnectPolicy(), failure) {
+		s.NoReconnect(reason)
+	}
+}
+
+func (s *State) GetFailure() string {
+	if s.failure == "" {
+		return NetworkFailure
+	}
+	return s.failure
 }
 
 func (s *State) stopWatch() {
@@ -148,12 +171,14 @@ func (s *State) SetConnecting() {
 		"reconnect":        s.conn.Profile.Reconnect,
 	}).Info("profile: Connecting")
 
-	s.conn.Data.Status = Connecting
+	s.conn.Data.SetStatus(Connecting, "start")
+	s.conn.Data.runHook(hooks.Connecting)
 
 	return
 }
 
 func (s *State) AddPath(pth string) {
+	s.addJournal(journal.FileEntry, pth, "")
 	s.tempPaths = append(s.tempPaths, pth)
 }
 
@@ -0,0 +1,125 @@
+package connection
+
+import (
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/sirupsen/logrus"
+)
+
+type Status string
+
+const (
+	Initial        Status = ""
+	Connecting     Status = "connecting"
+	Authenticating Status = "authenticating"
+	Connected      Status = "connected"
+	Disconnecting  Status = "disconnecting"
+	Disconnected   Status = "disconnected"
+)
+
+var transitions = map[Status]set.Set{
+	Initial: set.NewSet(
+		Connecting,
+		Disconnecting,
+		Disconnected,
+	),
+	Connecting: set.NewSet(
+		Authenticating,
+		Connected,
+		Disconnecting,
+		Disconnected,
+	),
+	Authenticating: set.NewSet(
+		Connecting,
+		Connected,
+		Disconnecting,
+		Disconnected,
+	),
+	Connected: set.NewSet(
+		Disconnecting,
+		Disconnected,
+	),
+	Disconnecting: set.NewSet(
+		Disconnected,
+	),
+	Disconnected: set.NewSet(),
+}
+
+type TransitionEventData struct {
+	Id    string `json:"id"`
+	Old   Status `json:"old"`
+	New   Status `json:"new"`
+	Cause string `json:"cause"`
+}
+
+type statusMachine struct {
+	lock sync.Mutex
+}
+
+func (s Status) CanTransition(status Status) bool {
+	allowed := transitions[s]
+	if allowed == nil {
+		return false
+	}
+	return allowed.Contains(status)
+}
+
+func (d *Data) GetStatus() Status {
+	d.statusMachine.lock.Lock()
+	defer d.statusMachine.lock.Unlock()
+
+	return d.Status
+}
+
+// SetStatus moves the connection to the new status, illegal transitions
+// are logged and rejected. Setting the current status is a no-op.
+func (d *Data) SetStatus(status Status, cause string) bool {
+	d.statusMachine.lock.Lock()
+
+	old := d.Status
+	if old == status {
+		d.statusMachine.lock.Unlock()
+		return true
+	}
+
+	if !old.CanTransition(status) {
+		d.statusMachine.lock.Unlock()
+
+		fields := logrus.Fields{
+			"old_status": old,
+			"new_status": status,
+			"cause":      cause,
+		}
+		if d.conn != nil {
+			fields = d.conn.Fields(fields)
+		}
+		logrus.WithFields(fields).Warn(
+			"connection: Rejected illegal status transition")
+
+		return false
+	}
+
+	d.Status = status
+	d.recordStatus(status)
+	d.statusMachine.lock.Unlock()
+
+	if status == Connected {
+		GlobalBackoff.Connected(d.Id)
+		d.recordHandshake(true)
+	}
+
+	evt := &event.Event{
+		Type: "status_transition",
+		Data: &TransitionEventData{
+			Id:    d.Id,
+			Old:   old,
+			New:   status,
+			Cause: cause,
+		},
+	}
+	evt.Init()
+
+	return true
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/pritunl-client-electron/service/health"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -25,6 +26,9 @@ type StoreData struct {
 	Id string `json:"id"`
 	*Data
 	*Condition
+	Backoff *Backoff                  `json:"backoff,omitempty"`
+	Pause   *Pause                    `json:"pause,omitempty"`
+	Health  map[string]*health.Remote `json:"health,omitempty"`
 }
 
 type Store struct {
@@ -245,8 +249,33 @@ func (s *Store) GetAllData() (prfls map[string]*StoreData) {
 	s.cleanState()
 	for _, conn := range s.conns {
 		prfls[conn.Id] = &StoreData{
-			Id:   conn.Id,
-			Data: conn.Data,
+			Id:     conn.Id,
+			Data:   conn.Data,
+			Health: health.Get(conn.Id),
+		}
+	}
+
+	for prflId, backoff := range GlobalBackoff.GetAll() {
+		data := prfls[prflId]
+		if data != nil {
+			data.Backoff = backoff
+		} else if backoff.Pending || backoff.Exhausted {
+			prfls[prflId] = &StoreData{
+				Id:      prflId,
+				Backoff: backoff,
+			}
+		}
+	}
+
+	for prflId, pause := range GlobalPause.GetAll() {
+		data := prfls[prflId]
+		if data != nil {
+			data.Pause = pause
+		} else {
+			prfls[prflId] = &StoreData{
+				Id:    prflId,
+				Pause: pause,
+			}
 		}
 	}
 
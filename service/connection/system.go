@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/killswitch"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
 	"github.com/pritunl/pritunl-client-electron/service/update"
 	"github.com/sirupsen/logrus"
@@ -46,8 +47,14 @@ func SyncSystemProfiles() (err error) {
 	for _, sPrfl := range sprfls {
 		conn := conns[sPrfl.Id]
 
-		if sPrfl.State {
+		if getSystemState(sPrfl, conn) {
 			if conn == nil {
+				if GlobalBackoff.IsBlocked(sPrfl.Id) ||
+					GlobalPause.IsPaused(sPrfl.Id) {
+
+					continue
+				}
+
 				conn, err = ImportSystemProfile(sPrfl)
 				if err != nil {
 					return
@@ -147,14 +154,20 @@ func SyncSystemProfiles() (err error) {
 					waiter.Done()
 				}(sPrfl)
 			}
-		} else if conn != nil {
-			update = true
-			waiter.Add(1)
-
-			go func() {
-				conn.Stop()
-				waiter.Done()
-			}()
+		} else {
+			if conn != nil {
+				update = true
+				waiter.Add(1)
+
+				go func() {
+					conn.Stop()
+					waiter.Done()
+				}()
+			}
+
+			if killswitch.IsActive(sPrfl.Id) {
+				DisableKillSwitch(sPrfl.Id)
+			}
 		}
 	}
 
@@ -22,6 +22,7 @@ Endpoint = {{.Endpoint}}
 
 var (
 	wgIfaceMacReg = regexp.MustCompile("\\((utun[0-9]+)\\)")
+	ovpnTunReg    = regexp.MustCompile("TUN/TAP device ([^ ]+) opened")
 	WgConfTempl   = template.Must(template.New("wg_conf").Parse(wgConfTempl))
 )
 
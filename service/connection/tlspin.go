@@ -0,0 +1,84 @@
+package connection
+
+import (
+	"net/http"
+
+	"github.com/pritunl/pritunl-client-electron/service/sprofile"
+	"github.com/pritunl/pritunl-client-electron/service/tlspin"
+	"github.com/sirupsen/logrus"
+)
+
+// getTlsPins returns the pins for the host, system profiles use the stored
+// pins which include pins trusted on first use
+func (c *Client) getTlsPins(host string) (pins []string, tofu bool) {
+
+	if !c.conn.Profile.SystemProfile {
+		pins = c.conn.Profile.TlsPins
+		return
+	}
+
+	sprfl := sprofile.Get(c.conn.Profile.Id)
+	if sprfl == nil {
+		pins = c.conn.Profile.TlsPins
+		return
+	}
+
+	pins, tofu = sprfl.GetTlsPins(host)
+
+	return
+}
+
+// verifyPeer returns the peer verify function for the host, tofuPin is
+// only set when the host has no stored pin
+func (c *Client) verifyPeer(host string,
+	tofuPin *string) tlspin.VerifyFunc {
+
+	pins, tofu := c.getTlsPins(host)
+	if !tofu {
+		tofuPin = nil
+	}
+
+	return tlspin.VerifyPeer(pins, tofuPin, func(err error) {
+		logrus.WithFields(c.conn.Fields(logrus.Fields{
+			"host":  host,
+			"error": err,
+		})).Error("connection: TLS certificate pin mismatch")
+
+		c.conn.State.Failure(VerificationFailure, "tls_pin_mismatch")
+		c.conn.Data.SendProfileEvent("tls_pin_mismatch")
+	})
+}
+
+// storeTlsPin saves the first use pin of the host for system profiles,
+// other profiles only use the configured pins
+func (c *Client) storeTlsPin(host, pin string) {
+	if pin == "" || !c.conn.Profile.SystemProfile {
+		return
+	}
+
+	logrus.WithFields(c.conn.Fields(logrus.Fields{
+		"host": host,
+		"pin":  pin,
+	})).Info("connection: Storing trusted TLS pin")
+
+	err := sprofile.SetTlsPinTofu(c.conn.Profile.Id, host, pin)
+	if err != nil {
+		logrus.WithFields(c.conn.Fields(logrus.Fields{
+			"host":  host,
+			"error": err,
+		})).Error("connection: Failed to store trusted TLS pin")
+	}
+}
+
+// getHttpClient returns the client for the host with the peer certificate
+// verified against the profile pins
+func (c *Client) getHttpClient(host string,
+	tofuPin *string) *http.Client {
+
+	verify := c.verifyPeer(host, tofuPin)
+	if verify == nil {
+		return clientInsecure
+	}
+
+	return tlspin.Client(clientInsecure, verify)
+}
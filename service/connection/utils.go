@@ -13,7 +13,6 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
-	"github.com/pritunl/pritunl-client-electron/service/command"
 	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/tuntap"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
@@ -170,15 +169,27 @@ func ParseAddress(input string) (addr string) {
 }
 
 func RestartProfiles(clean bool) (err error) {
+	err = restartProfiles(clean, false)
+	return
+}
+
+// WakeProfiles restarts the profiles after a system wake, the reconnect
+// policy sets the delay and limits the attempts
+func WakeProfiles() (err error) {
+	err = restartProfiles(false, true)
+	return
+}
+
+func restartProfiles(clean, wake bool) (err error) {
 	restartLock.Lock()
 	defer restartLock.Unlock()
 
 	conns := GlobalStore.GetAll()
-	prfls := []*Profile{}
+	reconns := []*Connection{}
 
 	for _, conn := range conns {
 		if conn.State.IsReconnect() {
-			prfls = append(prfls, conn.Profile)
+			reconns = append(reconns, conn)
 		}
 		conn.StopBackground()
 	}
@@ -201,7 +212,36 @@ func RestartProfiles(clean bool) (err error) {
 		}
 	}
 
-	for _, prfl := range prfls {
+	for _, conn := range reconns {
+		if wake {
+			policy := conn.Profile.GetReconnectPolicy()
+			if !IsRetryable(policy, NetworkFailure) {
+				logrus.WithFields(conn.Fields(nil)).Info(
+					"profile: Wake restart disabled by reconnect policy")
+				continue
+			}
+
+			delay, generation, ok := GlobalBackoff.Next(
+				conn.Id, policy, NetworkFailure,
+				!conn.Profile.HasReconnectPolicy())
+			if !ok {
+				logrus.WithFields(conn.Fields(nil)).Warn(
+					"profile: Wake restart with reconnect attempts exhausted")
+				continue
+			}
+
+			logrus.WithFields(conn.Fields(logrus.Fields{
+				"reconnect_delay": delay.String(),
+			})).Info("profile: Wake restart")
+			go conn.RestartDelay(delay, generation, false)
+
+			continue
+		}
+
+		prfl := conn.Profile
+
+		GlobalBackoff.Reset(prfl.Id)
+
 		go func(prfl *Profile) {
 			defer func() {
 				panc := recover()
@@ -319,27 +359,3 @@ func NetworkManagerSupport() bool {
 
 	return true
 }
-
-func HasAppArmor() bool {
-	exists, err := utils.ExistsFile("/usr/sbin/apparmor_status")
-	if err != nil {
-		return false
-	}
-
-	if !exists {
-		return false
-	}
-
-	cmd := command.Command("/usr/sbin/apparmor_status")
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-
-	if strings.Contains(string(output), "openvpn") {
-		return true
-	}
-
-	return false
-}
@@ -17,6 +17,7 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/hooks"
 	"github.com/pritunl/pritunl-client-electron/service/network"
 	"github.com/pritunl/pritunl-client-electron/service/platform"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
@@ -37,12 +38,15 @@ type Wg struct {
 	wgConfPath2   string
 	connected     bool
 	lastHandshake int
+	handshakeLock sync.Mutex
 	bashPath      string
 	publicKey     string
 	privateKey    string
 	serverPubKey  string
 	ssoToken      string
 	ssoStart      time.Time
+	endpoints     []string
+	endpointIndex int
 }
 
 type WgConf struct {
@@ -74,7 +78,8 @@ func (w *Wg) Fields() logrus.Fields {
 		"wg_conf_path":      w.wgConfPath,
 		"wg_conf_path2":     w.wgConfPath2,
 		"wg_connected":      w.connected,
-		"wg_last_handshake": w.lastHandshake,
+		"wg_last_handshake": w.LastHandshake(),
+		"wg_endpoints":      w.endpoints,
 		"wg_pub_key":        w.publicKey != "",
 		"wg_priv_key":       w.privateKey != "",
 		"wg_server_pub_key": w.serverPubKey != "",
@@ -240,7 +245,12 @@ func (w *Wg) WatchConnection() (err error) {
 	}
 	interval = interval * 2
 
-	for i := 0; i < 50; i++ {
+	attempts := 50
+	if len(w.endpoints)*WgEndpointAttempts > attempts {
+		attempts = len(w.endpoints) * WgEndpointAttempts
+	}
+
+	for i := 0; i < attempts; i++ {
 		if w.conn.State.IsStop() {
 			w.conn.State.Close()
 			return
@@ -265,14 +275,22 @@ func (w *Wg) WatchConnection() (err error) {
 			return
 		}
 
-		if w.lastHandshake != 0 {
+		if w.LastHandshake() != 0 {
+			if !w.conn.Data.SetStatus(Connected, "wg_handshake") {
+				w.conn.State.Close()
+				return
+			}
 			w.connected = true
-			w.conn.Data.Status = Connected
 			w.conn.Data.Timestamp = time.Now().Unix() - 3
 			w.conn.Data.UpdateEvent()
+			w.conn.Data.runHook(hooks.Connected)
 			break
 		}
 
+		if i > 0 && i%WgEndpointAttempts == 0 {
+			w.nextEndpoint()
+		}
+
 		time.Sleep(500 * time.Millisecond)
 	}
 
@@ -281,8 +299,9 @@ func (w *Wg) WatchConnection() (err error) {
 		return
 	}
 
-	if w.lastHandshake == 0 {
+	if w.LastHandshake() == 0 {
 		w.conn.Data.SendProfileEvent("handshake_timeout")
+		w.conn.Data.recordHandshake(false)
 
 		logrus.WithFields(w.conn.Fields(logrus.Fields{
 			"error": err,
@@ -335,6 +354,9 @@ func (w *Wg) WatchConnection() (err error) {
 		var final bool
 		for i := 0; i < 8; i++ {
 			data, final, err = w.ping()
+			if err != nil {
+				GlobalMetrics.PingFailure(w.conn.Id)
+			}
 			if err == nil || final || time.Since(start) > 15*time.Second {
 				break
 			}
@@ -374,23 +396,37 @@ func (w *Wg) WatchConnection() (err error) {
 			w.conn.State.Close()
 			return
 		}
+
+		err = w.updateHandshake()
+		if err != nil {
+			logrus.WithFields(w.conn.Fields(logrus.Fields{
+				"error": err,
+			})).Warn("connection: Failed to update handshake status")
+			err = nil
+		}
 	}
 }
 
-func (w *Wg) updateHandshake() (err error) {
-	iface := ""
-	if runtime.GOOS == "darwin" {
-		iface = w.conn.Data.WgTunIface
-	} else {
-		iface = w.conn.Data.Iface
-	}
+func (w *Wg) LastHandshake() int {
+	w.handshakeLock.Lock()
+	defer w.handshakeLock.Unlock()
+
+	return w.lastHandshake
+}
+
+func (w *Wg) setLastHandshake(lastHandshake int) {
+	w.handshakeLock.Lock()
+	w.lastHandshake = lastHandshake
+	w.handshakeLock.Unlock()
+}
 
+func (w *Wg) updateHandshake() (err error) {
 	output, err := utils.ExecCombinedOutputLogged(
 		[]string{
 			"No such device",
 			"access interface",
 		},
-		w.wgPath, "show", iface,
+		w.wgPath, "show", w.getIface(),
 		"latest-handshakes",
 	)
 	if err != nil {
@@ -409,12 +445,12 @@ func (w *Wg) updateHandshake() (err error) {
 				continue
 			}
 
-			w.lastHandshake = lastHandshake
+			w.setLastHandshake(lastHandshake)
 			return
 		}
 	}
 
-	w.lastHandshake = 0
+	w.setLastHandshake(0)
 	return
 }
 
@@ -439,7 +475,8 @@ func (w *Wg) ping() (data *PingData, final bool, err error) {
 	ctx := w.conn.Client.GetContext()
 	defer ctx.Cancel()
 
-	res, err := w.conn.Client.EncRequest(ctx, "PUT", reqUrl, ciph, reqBx)
+	res, tofuPin, err := w.conn.Client.EncRequest(
+		ctx, "PUT", reqUrl, ciph, reqBx)
 	if err != nil {
 		return
 	}
@@ -480,6 +517,8 @@ func (w *Wg) ping() (data *PingData, final bool, err error) {
 		return
 	}
 
+	w.conn.Client.storeTlsPin(reqUrl.Hostname(), tofuPin)
+
 	return
 }
 
@@ -519,12 +558,15 @@ func (w *Wg) writeWgConf(data *WgConf) (err error) {
 		addr += "," + data.Address6
 	}
 
+	w.endpoints = w.resolveEndpoints(data)
+	w.endpointIndex = 0
+
 	templData := WgConfData{
 		Address:    addr,
 		PrivateKey: w.privateKey,
 		PublicKey:  data.PublicKey,
 		AllowedIps: strings.Join(allowedIps, ","),
-		Endpoint:   fmt.Sprintf("%s:%d", data.Hostname, data.Port),
+		Endpoint:   w.endpoints[0],
 	}
 
 	if data.Mtu != 0 {
@@ -533,14 +575,14 @@ func (w *Wg) writeWgConf(data *WgConf) (err error) {
 	}
 
 	if !w.conn.Profile.DisableDns && len(data.DnsServers) > 0 &&
-		runtime.GOOS != "darwin" {
+		runtime.GOOS == "windows" {
 
 		templData.HasDns = true
 		templData.DnsServers = strings.Join(data.DnsServers, ",")
 	}
 
 	if !w.conn.Profile.DisableDns && len(data.SearchDomains) > 0 &&
-		runtime.GOOS != "darwin" {
+		runtime.GOOS == "windows" {
 
 		templData.HasDns = true
 		if templData.DnsServers != "" {
@@ -625,9 +667,13 @@ func (w *Wg) confWg(data *WgConf) (err error) {
 	w.conn.Data.WebNoSsl = data.WebNoSsl
 	w.conn.Data.DnsServers = data.DnsServers
 	w.conn.Data.SearchDomains = data.SearchDomains
+	w.conn.Data.Routes = data.Routes
+	w.conn.Data.Routes6 = data.Routes6
 
 	w.serverPubKey = data.PublicKey
 
+	w.addJournal(data)
+
 	switch runtime.GOOS {
 	case "darwin":
 		err = w.confWgMac()
@@ -645,6 +691,13 @@ func (w *Wg) confWg(data *WgConf) (err error) {
 		return
 	}
 
+	w.conn.Data.setDns(
+		w.conn.Data.Iface, data.DnsServers, data.SearchDomains)
+	w.conn.Data.updateIpv6Guard(
+		hasWgRoute(data.Routes, "0.0.0.0/0"),
+		hasWgRoute(data.Routes6, "::/0"),
+	)
+
 	return
 }
 
@@ -792,6 +845,7 @@ func (w *Wg) clearWgLinux() {
 			w.wgQuickPath,
 			"down", w.conn.Data.Iface,
 		)
+		w.removeJournal()
 	}
 }
 
@@ -0,0 +1,92 @@
+package connection
+
+import (
+	"net"
+	"runtime"
+	"strconv"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// WgEndpointAttempts is the number of handshake checks before moving to
+// the next endpoint address
+const WgEndpointAttempts = 14
+
+// resolveEndpoints returns an endpoint for each resolved address of the
+// server hostnames, the hostname is used if it cannot be resolved
+func (w *Wg) resolveEndpoints(data *WgConf) (endpoints []string) {
+	endpoints = []string{}
+	port := strconv.Itoa(data.Port)
+
+	for _, hostname := range []string{data.Hostname, data.Hostname6} {
+		if hostname == "" {
+			continue
+		}
+
+		remote := &Remote{
+			Host: hostname,
+		}
+		remote.Lookup()
+
+		addrs := remote.GetAddrs()
+		if len(addrs) == 0 {
+			endpoints = append(endpoints, net.JoinHostPort(hostname, port))
+			continue
+		}
+
+		for _, addr := range addrs {
+			endpoint := net.JoinHostPort(addr, port)
+			exists := false
+			for _, ep := range endpoints {
+				if ep == endpoint {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, net.JoinHostPort(data.Hostname, port))
+	}
+
+	return
+}
+
+func (w *Wg) getIface() string {
+	if runtime.GOOS == "darwin" {
+		return w.conn.Data.WgTunIface
+	}
+	return w.conn.Data.Iface
+}
+
+// nextEndpoint moves the peer to the next endpoint address
+func (w *Wg) nextEndpoint() {
+	if len(w.endpoints) < 2 {
+		return
+	}
+
+	w.endpointIndex = (w.endpointIndex + 1) % len(w.endpoints)
+	endpoint := w.endpoints[w.endpointIndex]
+
+	logrus.WithFields(w.conn.Fields(logrus.Fields{
+		"endpoint": endpoint,
+	})).Info("connection: WireGuard handshake pending, trying next endpoint")
+
+	_, err := utils.ExecCombinedOutputLogged(
+		nil,
+		w.wgPath, "set", w.getIface(),
+		"peer", w.serverPubKey,
+		"endpoint", endpoint,
+	)
+	if err != nil {
+		logrus.WithFields(w.conn.Fields(logrus.Fields{
+			"endpoint": endpoint,
+			"error":    err,
+		})).Error("connection: Failed to set WireGuard endpoint")
+	}
+}
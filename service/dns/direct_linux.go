@@ -0,0 +1,269 @@
+package dns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const directHeader = "# Generated by Pritunl Client\n"
+
+// direct writes resolv.conf with the servers and search domains of all
+// direct configurations, the original file is stored and restored when the
+// last configuration is cleared
+type direct struct{}
+
+func (d *direct) Name() string {
+	return DirectBackend
+}
+
+func (d *direct) getBackupPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-resolv.conf")
+}
+
+// backup returns the original file, the backup is replaced when
+// resolv.conf was written by another program since the last update
+func (d *direct) backup() (data string, err error) {
+	pth := d.getBackupPath()
+
+	curByt, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "dns: Failed to read resolv.conf"),
+			}
+			return
+		}
+	}
+	cur := string(curByt)
+
+	if strings.HasPrefix(cur, directHeader) {
+		dataByt, e := ioutil.ReadFile(pth)
+		if e == nil {
+			data = string(dataByt)
+			return
+		}
+		if !os.IsNotExist(e) {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "dns: Failed to read resolv.conf backup"),
+			}
+			return
+		}
+	}
+
+	data = cur
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(pth, curByt, 0644)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dns: Failed to write resolv.conf backup"),
+		}
+		return
+	}
+
+	return
+}
+
+func uniq(vals []string) (uniqVals []string) {
+	uniqVals = []string{}
+	found := map[string]bool{}
+
+	for _, val := range vals {
+		if found[val] {
+			continue
+		}
+		found[val] = true
+		uniqVals = append(uniqVals, val)
+	}
+
+	return
+}
+
+// render merges the direct configurations into the original file, the
+// original servers and search domains are removed when a configuration is
+// forced
+func (d *direct) render(orig string) (data string) {
+	servers := []string{}
+	domains := []string{}
+	force := false
+
+	ifaces := []string{}
+	for iface := range configs {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	for _, forced := range []bool{true, false} {
+		for _, iface := range ifaces {
+			conf := configs[iface]
+			if conf.Backend != DirectBackend || conf.Force != forced {
+				continue
+			}
+			if conf.Force {
+				force = true
+			}
+
+			servers4, servers6 := conf.servers()
+			servers = append(servers, servers4...)
+			servers = append(servers, servers6...)
+			domains = append(domains, conf.domains()...)
+		}
+	}
+
+	other := []string{}
+	for _, line := range strings.Split(orig, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") ||
+			strings.HasPrefix(fields[0], ";") {
+
+			continue
+		}
+
+		switch fields[0] {
+		case "nameserver":
+			if !force && len(fields) > 1 {
+				servers = append(servers, fields[1])
+			}
+		case "search", "domain":
+			if !force {
+				domains = append(domains, fields[1:]...)
+			}
+		default:
+			other = append(other, line)
+		}
+	}
+
+	servers = uniq(servers)
+	domains = uniq(domains)
+
+	data = directHeader
+	if len(domains) > 0 {
+		data += "search " + strings.Join(domains, " ") + "\n"
+	}
+	for _, server := range servers {
+		data += "nameserver " + server + "\n"
+	}
+	for _, line := range other {
+		data += line + "\n"
+	}
+
+	return
+}
+
+// restore writes the original file if resolv.conf was not modified by
+// another program
+func (d *direct) restore() (err error) {
+	pth := d.getBackupPath()
+
+	exists, err := utils.ExistsFile(pth)
+	if err != nil {
+		return
+	}
+	if !exists {
+		return
+	}
+
+	cur, _ := ioutil.ReadFile(resolvConfPath)
+	if strings.HasPrefix(string(cur), directHeader) {
+		data, e := ioutil.ReadFile(pth)
+		if e != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "dns: Failed to read resolv.conf backup"),
+			}
+			return
+		}
+
+		err = ioutil.WriteFile(resolvConfPath, data, 0644)
+		if err != nil {
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "dns: Failed to restore resolv.conf"),
+			}
+			return
+		}
+	}
+
+	err = os.Remove(pth)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dns: Failed to remove resolv.conf backup"),
+		}
+		return
+	}
+
+	return
+}
+
+func (d *direct) update() (err error) {
+	hasConf := false
+	for _, conf := range configs {
+		if conf.Backend == DirectBackend {
+			hasConf = true
+			break
+		}
+	}
+
+	if !hasConf {
+		err = d.restore()
+		if err != nil {
+			return
+		}
+		return
+	}
+
+	orig, err := d.backup()
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(resolvConfPath, []byte(d.render(orig)), 0644)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dns: Failed to write resolv.conf"),
+		}
+		return
+	}
+
+	return
+}
+
+func (d *direct) Set(conf *Config) (err error) {
+	err = d.update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (d *direct) Clear(conf *Config) (err error) {
+	err = d.update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (d *direct) Check(conf *Config) (ok bool, err error) {
+	ok, err = checkResolvConf(conf)
+	if err != nil {
+		return
+	}
+
+	return
+}
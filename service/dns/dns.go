@@ -0,0 +1,287 @@
+package dns
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ResolvedBackend       = "resolved"
+	NetworkManagerBackend = "network_manager"
+	ResolvconfBackend     = "resolvconf"
+	DirectBackend         = "direct"
+)
+
+const RestoreLimit = 10 * time.Second
+
+var (
+	configs = map[string]*Config{}
+	lock    = sync.Mutex{}
+)
+
+// Config is the DNS configuration of a tunnel interface, forced
+// configurations take precedence over the DNS servers of other interfaces
+type Config struct {
+	Iface         string   `json:"iface"`
+	Servers       []string `json:"servers"`
+	SearchDomains []string `json:"search_domains"`
+	Force         bool     `json:"force"`
+	Backend       string   `json:"backend"`
+	restored      time.Time
+}
+
+func (c *Config) Copy() (conf *Config) {
+	conf = &Config{
+		Iface:         c.Iface,
+		Servers:       append([]string{}, c.Servers...),
+		SearchDomains: append([]string{}, c.SearchDomains...),
+		Force:         c.Force,
+		Backend:       c.Backend,
+		restored:      c.restored,
+	}
+
+	return
+}
+
+// servers returns the valid IPv4 and IPv6 servers
+func (c *Config) servers() (servers4, servers6 []string) {
+	servers4 = []string{}
+	servers6 = []string{}
+
+	for _, server := range c.Servers {
+		ip := net.ParseIP(strings.TrimSpace(server))
+		if ip == nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			servers4 = append(servers4, ip.String())
+		} else {
+			servers6 = append(servers6, ip.String())
+		}
+	}
+
+	return
+}
+
+// domains returns the search domains without duplicates
+func (c *Config) domains() (domains []string) {
+	domains = []string{}
+	found := map[string]bool{}
+
+	for _, domain := range c.SearchDomains {
+		domain = strings.Trim(strings.TrimSpace(domain), ".")
+		if domain == "" || found[domain] {
+			continue
+		}
+		found[domain] = true
+		domains = append(domains, domain)
+	}
+
+	return
+}
+
+// Backend applies the DNS configuration of an interface to the system
+// resolver, Clear must succeed when the interface no longer exists and
+// Check returns false when the configuration is no longer effective
+type Backend interface {
+	Name() string
+	Set(conf *Config) error
+	Clear(conf *Config) error
+	Check(conf *Config) (bool, error)
+}
+
+// Restore is a configuration that was applied again after it was removed
+// by another program
+type Restore struct {
+	Iface   string
+	Backend string
+	Writer  string
+}
+
+func set(conf *Config) (err error) {
+	backend := detectBackend()
+
+	curConf := configs[conf.Iface]
+	if curConf != nil && curConf.Backend != backend.Name() {
+		delete(configs, conf.Iface)
+
+		e := getBackend(curConf.Backend).Clear(curConf)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"iface":   curConf.Iface,
+				"backend": curConf.Backend,
+				"error":   e,
+			}).Error("dns: Failed to clear previous DNS configuration")
+		}
+	}
+
+	conf.Backend = backend.Name()
+	configs[conf.Iface] = conf
+
+	err = backend.Set(conf)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Set applies the configuration with the detected backend, an existing
+// configuration of the interface is replaced
+func Set(conf *Config) (err error) {
+	if !Supported {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	conf = conf.Copy()
+
+	err = set(conf)
+	if err != nil {
+		delete(configs, conf.Iface)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"iface":          conf.Iface,
+		"backend":        conf.Backend,
+		"servers":        conf.Servers,
+		"search_domains": conf.SearchDomains,
+		"force":          conf.Force,
+	}).Info("dns: Configured DNS")
+
+	return
+}
+
+// Repair applies the configurations that are no longer effective again,
+// the program that modified the resolver configuration is identified when
+// possible
+func Repair() (restores []*Restore, err error) {
+	if !Supported {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, conf := range configs {
+		if time.Since(conf.restored) < RestoreLimit {
+			continue
+		}
+
+		ok, e := getBackend(conf.Backend).Check(conf)
+		if e != nil {
+			err = e
+			continue
+		}
+		if ok {
+			continue
+		}
+
+		restore := &Restore{
+			Iface:  conf.Iface,
+			Writer: getWriter(),
+		}
+
+		conf.restored = time.Now()
+		e = set(conf)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		restore.Backend = conf.Backend
+		restores = append(restores, restore)
+	}
+
+	return
+}
+
+// Clear removes the configuration of the interface with the backend that
+// applied it
+func Clear(iface string) (err error) {
+	if !Supported {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	conf := configs[iface]
+	if conf == nil {
+		return
+	}
+	delete(configs, iface)
+
+	err = getBackend(conf.Backend).Clear(conf)
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"iface":   conf.Iface,
+		"backend": conf.Backend,
+	}).Info("dns: Cleared DNS")
+
+	return
+}
+
+// Get returns a copy of the configuration of the interface
+func Get(iface string) (conf *Config) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	curConf := configs[iface]
+	if curConf != nil {
+		conf = curConf.Copy()
+	}
+
+	return
+}
+
+// GetBackend returns the name of the backend used for new configurations
+func GetBackend() string {
+	if !Supported {
+		return ""
+	}
+
+	return detectBackend().Name()
+}
+
+// Recover removes the configuration of the interface left by a previous
+// run of the service from the backend, every backend is cleared when the
+// backend is not known
+func Recover(iface, backendName string) (err error) {
+	if !Supported {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	conf := &Config{
+		Iface:   iface,
+		Backend: backendName,
+	}
+
+	backends := getBackends()
+	if backendName != "" {
+		backends = []Backend{getBackend(backendName)}
+	}
+
+	for _, backend := range backends {
+		e := backend.Clear(conf)
+		if e != nil {
+			err = e
+		}
+	}
+
+	return
+}
@@ -0,0 +1,210 @@
+package dns
+
+import (
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+)
+
+const (
+	Supported      = true
+	resolvConfPath = "/etc/resolv.conf"
+)
+
+var backends = map[string]Backend{
+	ResolvedBackend:       &resolved{},
+	NetworkManagerBackend: &networkManager{},
+	ResolvconfBackend:     &resolvconf{},
+	DirectBackend:         &direct{},
+}
+
+func getBackends() []Backend {
+	return []Backend{
+		backends[ResolvedBackend],
+		backends[NetworkManagerBackend],
+		backends[ResolvconfBackend],
+		backends[DirectBackend],
+	}
+}
+
+func getBackend(name string) Backend {
+	backend := backends[name]
+	if backend == nil {
+		backend = backends[DirectBackend]
+	}
+	return backend
+}
+
+// detectBackend returns the backend that manages resolv.conf, the file is
+// written directly when no resolver manager is available
+func detectBackend() Backend {
+	resolvData, _ := ioutil.ReadFile(resolvConfPath)
+	resolvDataStr := string(resolvData)
+
+	if strings.Contains(resolvDataStr, "systemd-resolved") ||
+		strings.Contains(resolvDataStr, "127.0.0.53") {
+
+		busctlPath, _ := exec.LookPath("busctl")
+		if busctlPath != "" {
+			return backends[ResolvedBackend]
+		}
+	}
+
+	if strings.Contains(resolvDataStr, "NetworkManager") {
+		nmcliPath, _ := exec.LookPath("nmcli")
+		if nmcliPath != "" {
+			return backends[NetworkManagerBackend]
+		}
+	}
+
+	resolvconfPath, _ := exec.LookPath("resolvconf")
+	if resolvconfPath != "" {
+		return backends[ResolvconfBackend]
+	}
+
+	return backends[DirectBackend]
+}
+
+// getIfaceIndex returns zero when the interface does not exist
+func getIfaceIndex(name string) (index int, err error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such network interface") {
+			err = nil
+			return
+		}
+
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dns: Failed to get interface"),
+		}
+		return
+	}
+
+	index = iface.Index
+	return
+}
+
+func parseResolvConf(data string) (servers, domains []string) {
+	servers = []string{}
+	domains = []string{}
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "nameserver":
+			ip := net.ParseIP(fields[1])
+			if ip != nil {
+				servers = append(servers, ip.String())
+			}
+		case "search", "domain":
+			for _, domain := range fields[1:] {
+				domains = append(domains, strings.Trim(domain, "."))
+			}
+		}
+	}
+
+	return
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// checkResolvConf returns false if the first server or search domain of
+// the configuration is missing from resolv.conf, forced configurations
+// must also provide the first server
+func checkResolvConf(conf *Config) (ok bool, err error) {
+	data, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dns: Failed to read resolv.conf"),
+		}
+		return
+	}
+
+	servers, domains := parseResolvConf(string(data))
+
+	servers4, servers6 := conf.servers()
+	confServers := append(servers4, servers6...)
+	if len(confServers) > 0 {
+		if !contains(servers, confServers[0]) {
+			return
+		}
+		if conf.Force && !contains(confServers, servers[0]) {
+			return
+		}
+	}
+
+	confDomains := conf.domains()
+	if len(confDomains) > 0 && !contains(domains, confDomains[0]) {
+		return
+	}
+
+	ok = true
+	return
+}
+
+// getWriter identifies the program that last wrote resolv.conf from the
+// header of the file or the running DHCP clients
+func getWriter() string {
+	data, _ := ioutil.ReadFile(resolvConfPath)
+	header := strings.ToLower(string(data))
+	if len(header) > 512 {
+		header = header[:512]
+	}
+
+	switch {
+	case strings.Contains(header, "networkmanager"):
+		return "NetworkManager"
+	case strings.Contains(header, "systemd-resolved"):
+		return "systemd-resolved"
+	case strings.Contains(header, "openresolv"),
+		strings.Contains(header, "resolvconf"):
+
+		return "resolvconf"
+	case strings.Contains(header, "dhcpcd"):
+		return "dhcpcd"
+	case strings.Contains(header, "netconfig"):
+		return "netconfig"
+	case strings.Contains(header, "connman"):
+		return "connman"
+	}
+
+	procs, _ := ioutil.ReadDir("/proc")
+	for _, proc := range procs {
+		if !proc.IsDir() {
+			continue
+		}
+
+		comm, e := ioutil.ReadFile(
+			filepath.Join("/proc", proc.Name(), "comm"))
+		if e != nil {
+			continue
+		}
+
+		switch strings.TrimSpace(string(comm)) {
+		case "dhclient":
+			return "dhclient"
+		case "dhcpcd":
+			return "dhcpcd"
+		case "udhcpc":
+			return "udhcpc"
+		}
+	}
+
+	return ""
+}
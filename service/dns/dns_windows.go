@@ -0,0 +1,36 @@
+package dns
+
+const Supported = false
+
+func getBackends() []Backend {
+	return []Backend{}
+}
+
+func getBackend(name string) Backend {
+	return nil
+}
+
+func detectBackend() Backend {
+	return nil
+}
+
+func getWriter() string {
+	return ""
+}
+
+func Watch() (events chan bool, err error) {
+	events = make(chan bool)
+	return
+}
+
+func IsLinkScoped() bool {
+	return false
+}
+
+func SetGlobal(servers []string) (err error) {
+	return
+}
+
+func ClearGlobal() (err error) {
+	return
+}
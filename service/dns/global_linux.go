@@ -0,0 +1,104 @@
+package dns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const resolvedDropInPath = "/etc/systemd/resolved.conf.d/pritunl.conf"
+
+// IsLinkScoped returns true when the backend sends queries for the servers
+// of an interface through the interface, loopback servers such as the DNS
+// stub can not be set on a tunnel interface and must use SetGlobal
+func IsLinkScoped() bool {
+	return detectBackend().Name() == ResolvedBackend
+}
+
+func reloadResolved() (err error) {
+	_, err = utils.ExecCombinedOutput(
+		"systemctl", "reload", "systemd-resolved.service")
+	if err == nil {
+		return
+	}
+
+	// Reload is not supported before systemd 251, the links are restored
+	// by the DNS watch after a restart
+	logrus.WithFields(logrus.Fields{
+		"error": err,
+	}).Warn("dns: Failed to reload resolved, restarting")
+
+	_, err = utils.ExecCombinedOutputLogged(
+		nil, "systemctl", "restart", "systemd-resolved.service")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// SetGlobal sets the global servers of systemd-resolved with a drop-in
+// configuration, the root routing domain sends all queries without a
+// more specific interface domain to the servers
+func SetGlobal(servers []string) (err error) {
+	data := fmt.Sprintf("# Generated by Pritunl Client\n"+
+		"[Resolve]\nDNS=%s\nDomains=~.\n", strings.Join(servers, " "))
+
+	curData, _ := ioutil.ReadFile(resolvedDropInPath)
+	if string(curData) == data {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(resolvedDropInPath), 0755)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dns: Failed to create resolved conf dir"),
+		}
+		return
+	}
+
+	err = utils.CreateWrite(resolvedDropInPath, data, 0644)
+	if err != nil {
+		return
+	}
+
+	err = reloadResolved()
+	if err != nil {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"servers": servers,
+	}).Info("dns: Configured resolved global DNS")
+
+	return
+}
+
+// ClearGlobal removes the global servers set with SetGlobal
+func ClearGlobal() (err error) {
+	exists, err := utils.ExistsFile(resolvedDropInPath)
+	if err != nil || !exists {
+		return
+	}
+
+	err = utils.Remove(resolvedDropInPath)
+	if err != nil {
+		return
+	}
+
+	err = reloadResolved()
+	if err != nil {
+		return
+	}
+
+	logrus.Info("dns: Removed resolved global DNS")
+
+	return
+}
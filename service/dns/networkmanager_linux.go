@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"strings"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	nmPriority      = "50"
+	nmForcePriority = "-50"
+)
+
+// networkManager modifies the active settings of the interface device in
+// NetworkManager, forced configurations use a negative priority to exclude
+// the DNS servers of other devices
+type networkManager struct{}
+
+func (n *networkManager) Name() string {
+	return NetworkManagerBackend
+}
+
+func (n *networkManager) modify(iface string, servers4, servers6,
+	domains []string, priority string) (err error) {
+
+	_, err = utils.ExecCombinedOutputLogged(
+		nil,
+		"nmcli", "device", "modify", iface,
+		"ipv4.dns", strings.Join(servers4, ","),
+		"ipv4.dns-search", strings.Join(domains, ","),
+		"ipv4.dns-priority", priority,
+		"ipv6.dns", strings.Join(servers6, ","),
+		"ipv6.dns-search", strings.Join(domains, ","),
+		"ipv6.dns-priority", priority,
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *networkManager) Set(conf *Config) (err error) {
+	index, err := getIfaceIndex(conf.Iface)
+	if err != nil {
+		return
+	}
+	if index == 0 {
+		return
+	}
+
+	priority := nmPriority
+	if conf.Force {
+		priority = nmForcePriority
+	}
+
+	servers4, servers6 := conf.servers()
+
+	err = n.modify(conf.Iface, servers4, servers6,
+		conf.domains(), priority)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *networkManager) Clear(conf *Config) (err error) {
+	index, err := getIfaceIndex(conf.Iface)
+	if err != nil {
+		return
+	}
+	if index == 0 {
+		return
+	}
+
+	_ = n.modify(conf.Iface, []string{}, []string{}, []string{}, "0")
+
+	return
+}
+
+func (n *networkManager) Check(conf *Config) (ok bool, err error) {
+	ok, err = checkResolvConf(conf)
+	if err != nil {
+		return
+	}
+
+	return
+}
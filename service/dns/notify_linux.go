@@ -0,0 +1,125 @@
+package dns
+
+import (
+	"path/filepath"
+	"runtime/debug"
+	"unsafe"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const notifyMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO |
+	unix.IN_CREATE | unix.IN_DELETE
+
+// Watch sends on the channel when resolv.conf or the target of the
+// resolv.conf symlink is modified
+func Watch() (events chan bool, err error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dns: Failed to init inotify"),
+		}
+		return
+	}
+
+	names := map[string]bool{
+		filepath.Base(resolvConfPath): true,
+	}
+	dirs := []string{
+		filepath.Dir(resolvConfPath),
+	}
+
+	target, e := filepath.EvalSymlinks(resolvConfPath)
+	if e == nil && target != resolvConfPath {
+		names[filepath.Base(target)] = true
+		dirs = append(dirs, filepath.Dir(target))
+	}
+
+	watches := map[int]bool{}
+	for _, dir := range dirs {
+		wd, e := unix.InotifyAddWatch(fd, dir, notifyMask)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"path":  dir,
+				"error": e,
+			}).Warn("dns: Failed to watch resolv.conf directory")
+			continue
+		}
+		watches[wd] = true
+	}
+
+	if len(watches) == 0 {
+		unix.Close(fd)
+		err = &errortypes.ReadError{
+			errors.New("dns: Failed to watch resolv.conf"),
+		}
+		return
+	}
+
+	events = make(chan bool, 1)
+
+	go func() {
+		defer func() {
+			panc := recover()
+			if panc != nil {
+				logrus.WithFields(logrus.Fields{
+					"trace": string(debug.Stack()),
+					"panic": panc,
+				}).Error("dns: Watch panic")
+			}
+		}()
+		defer unix.Close(fd)
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.PathMax))
+		for {
+			n, e := unix.Read(fd, buf)
+			if e != nil {
+				if e == unix.EINTR {
+					continue
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"error": e,
+				}).Error("dns: Failed to read inotify events")
+				return
+			}
+
+			matched := false
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				evt := (*unix.InotifyEvent)(
+					unsafe.Pointer(&buf[offset]))
+				nameStart := offset + unix.SizeofInotifyEvent
+				nameEnd := nameStart + int(evt.Len)
+				if nameEnd > n {
+					break
+				}
+
+				name := string(buf[nameStart:nameEnd])
+				for i, c := range name {
+					if c == 0 {
+						name = name[:i]
+						break
+					}
+				}
+
+				if names[name] {
+					matched = true
+				}
+
+				offset = nameEnd
+			}
+
+			if matched {
+				select {
+				case events <- true:
+				default:
+				}
+			}
+		}
+	}()
+
+	return
+}
@@ -0,0 +1,86 @@
+package dns
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+// resolvconf adds a record for the interface with resolvconf, forced
+// configurations are marked exclusive when openresolv is installed
+type resolvconf struct{}
+
+func (r *resolvconf) Name() string {
+	return ResolvconfBackend
+}
+
+func (r *resolvconf) getRecord(iface string) string {
+	return "tun." + iface
+}
+
+func (r *resolvconf) isOpenresolv(resolvconfPath string) bool {
+	output, _ := utils.ExecCombinedOutput(resolvconfPath, "--version")
+	return strings.Contains(strings.ToLower(output), "openresolv")
+}
+
+func (r *resolvconf) Set(conf *Config) (err error) {
+	resolvconfPath, err := exec.LookPath("resolvconf")
+	if err != nil {
+		err = &errortypes.NotFoundError{
+			errors.Wrap(err, "dns: Failed to find resolvconf"),
+		}
+		return
+	}
+
+	data := ""
+	domains := conf.domains()
+	if len(domains) > 0 {
+		data += "search " + strings.Join(domains, " ") + "\n"
+	}
+	servers4, servers6 := conf.servers()
+	for _, server := range append(servers4, servers6...) {
+		data += "nameserver " + server + "\n"
+	}
+
+	args := []string{
+		"-a", r.getRecord(conf.Iface),
+		"-m", "0",
+	}
+	if conf.Force && r.isOpenresolv(resolvconfPath) {
+		args = append(args, "-x")
+	}
+
+	_, err = utils.ExecInputOutput(data, resolvconfPath, args...)
+	if err != nil {
+		return
+	}
+
+	_, _ = utils.ExecCombinedOutput(resolvconfPath, "-u")
+
+	return
+}
+
+func (r *resolvconf) Clear(conf *Config) (err error) {
+	resolvconfPath, _ := exec.LookPath("resolvconf")
+	if resolvconfPath == "" {
+		return
+	}
+
+	_, _ = utils.ExecCombinedOutput(
+		resolvconfPath, "-d", r.getRecord(conf.Iface), "-f")
+	_, _ = utils.ExecCombinedOutput(resolvconfPath, "-u")
+
+	return
+}
+
+func (r *resolvconf) Check(conf *Config) (ok bool, err error) {
+	ok, err = checkResolvConf(conf)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,298 @@
+package dns
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	resolvedDest  = "org.freedesktop.resolve1"
+	resolvedPath  = "/org/freedesktop/resolve1"
+	resolvedIface = "org.freedesktop.resolve1.Manager"
+)
+
+// resolved configures the link of the interface in systemd-resolved over
+// D-Bus, forced configurations add the root routing domain to send all
+// queries to the link
+type resolved struct{}
+
+func (r *resolved) Name() string {
+	return ResolvedBackend
+}
+
+func (r *resolved) call(method, signature string, args ...string) (
+	err error) {
+
+	_, err = utils.ExecCombinedOutputLogged(
+		nil,
+		"busctl", append([]string{
+			"call",
+			resolvedDest,
+			resolvedPath,
+			resolvedIface,
+			method,
+			signature,
+		}, args...)...,
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *resolved) Set(conf *Config) (err error) {
+	index, err := getIfaceIndex(conf.Iface)
+	if err != nil {
+		return
+	}
+	if index == 0 {
+		return
+	}
+	indexStr := strconv.Itoa(index)
+
+	servers4, servers6 := conf.servers()
+	servers := append(servers4, servers6...)
+	if len(servers) > 0 {
+		args := []string{
+			indexStr,
+			strconv.Itoa(len(servers)),
+		}
+		for _, server := range servers {
+			ip := net.ParseIP(server)
+			addr := ip.To4()
+			family := "2"
+			if addr == nil {
+				addr = ip.To16()
+				family = "10"
+			}
+
+			args = append(args, family, strconv.Itoa(len(addr)))
+			for _, b := range addr {
+				args = append(args, strconv.Itoa(int(b)))
+			}
+		}
+
+		err = r.call("SetLinkDNS", "ia(iay)", args...)
+		if err != nil {
+			return
+		}
+	}
+
+	domains := conf.domains()
+	args := []string{
+		indexStr,
+	}
+	count := len(domains)
+	if conf.Force {
+		count += 1
+	}
+	args = append(args, strconv.Itoa(count))
+	for _, domain := range domains {
+		args = append(args, domain, "false")
+	}
+	if conf.Force {
+		args = append(args, ".", "true")
+	}
+
+	if count > 0 {
+		err = r.call("SetLinkDomains", "ia(sb)", args...)
+		if err != nil {
+			return
+		}
+	}
+
+	if conf.Force {
+		e := r.call("SetLinkDefaultRoute", "ib", indexStr, "true")
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"iface": conf.Iface,
+				"error": e,
+			}).Warn("dns: Failed to set resolved default route")
+		}
+	}
+
+	_, _ = utils.ExecCombinedOutput(
+		"busctl",
+		"call",
+		resolvedDest,
+		resolvedPath,
+		resolvedIface,
+		"FlushCaches",
+	)
+
+	return
+}
+
+func (r *resolved) Clear(conf *Config) (err error) {
+	index, err := getIfaceIndex(conf.Iface)
+	if err != nil {
+		return
+	}
+	if index == 0 {
+		return
+	}
+
+	_, _ = utils.ExecCombinedOutput(
+		"busctl",
+		"call",
+		resolvedDest,
+		resolvedPath,
+		resolvedIface,
+		"RevertLink",
+		"i", strconv.Itoa(index),
+	)
+
+	return
+}
+
+// getLinkProperty returns the fields of the property of the resolved link
+func (r *resolved) getLinkProperty(index int, property string) (
+	fields []string, err error) {
+
+	output, err := utils.ExecCombinedOutput(
+		"busctl",
+		"call",
+		resolvedDest,
+		resolvedPath,
+		resolvedIface,
+		"GetLink",
+		"i", strconv.Itoa(index),
+	)
+	if err != nil {
+		return
+	}
+
+	outputFields := strings.Fields(output)
+	if len(outputFields) != 2 {
+		err = &errortypes.ParseError{
+			errors.Newf("dns: Invalid resolved link '%s'", output),
+		}
+		return
+	}
+	linkPath := strings.Trim(outputFields[1], "\"")
+
+	output, err = utils.ExecCombinedOutput(
+		"busctl",
+		"get-property",
+		resolvedDest,
+		linkPath,
+		"org.freedesktop.resolve1.Link",
+		property,
+	)
+	if err != nil {
+		return
+	}
+
+	fields = strings.Fields(output)
+	if len(fields) < 2 {
+		err = &errortypes.ParseError{
+			errors.Newf("dns: Invalid resolved property '%s'", output),
+		}
+		return
+	}
+	fields = fields[2:]
+
+	return
+}
+
+// getLinkServers parses the a(iay) DNS property of the link
+func (r *resolved) getLinkServers(index int) (servers []string, err error) {
+	fields, err := r.getLinkProperty(index, "DNS")
+	if err != nil {
+		return
+	}
+
+	servers = []string{}
+	for i := 0; i+1 < len(fields); {
+		size, e := strconv.Atoi(fields[i+1])
+		if e != nil || i+2+size > len(fields) {
+			break
+		}
+
+		addr := make(net.IP, size)
+		for j := 0; j < size; j++ {
+			b, _ := strconv.Atoi(fields[i+2+j])
+			addr[j] = byte(b)
+		}
+		servers = append(servers, addr.String())
+
+		i += 2 + size
+	}
+
+	return
+}
+
+// getLinkDomains parses the a(sb) Domains property of the link, routing
+// only domains are prefixed with a tilde
+func (r *resolved) getLinkDomains(index int) (domains []string, err error) {
+	fields, err := r.getLinkProperty(index, "Domains")
+	if err != nil {
+		return
+	}
+
+	domains = []string{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		domain := strings.Trim(fields[i], "\"")
+		if fields[i+1] == "true" {
+			domain = "~" + domain
+		}
+		domains = append(domains, domain)
+	}
+
+	return
+}
+
+func (r *resolved) Check(conf *Config) (ok bool, err error) {
+	index, err := getIfaceIndex(conf.Iface)
+	if err != nil {
+		return
+	}
+	if index == 0 {
+		ok = true
+		return
+	}
+
+	servers4, servers6 := conf.servers()
+	confServers := append(servers4, servers6...)
+	if len(confServers) > 0 {
+		servers, e := r.getLinkServers(index)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, server := range confServers {
+			if !contains(servers, server) {
+				return
+			}
+		}
+	}
+
+	confDomains := conf.domains()
+	if len(confDomains) > 0 || conf.Force {
+		domains, e := r.getLinkDomains(index)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, domain := range confDomains {
+			if !contains(domains, domain) {
+				return
+			}
+		}
+		if conf.Force && !contains(domains, "~.") {
+			return
+		}
+	}
+
+	ok = true
+	return
+}
@@ -0,0 +1,111 @@
+package dnsstub
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	cacheMax     = 4096
+	cacheNegTtl  = 5 * time.Second
+	cacheMinTtl  = 1 * time.Second
+	DefaultCache = 30 * time.Second
+)
+
+type cacheEntry struct {
+	resp    []byte
+	expires time.Time
+}
+
+// cache stores responses for the lowest record ttl limited by the maximum
+// ttl, error and empty responses are stored for a shorter time
+type cache struct {
+	lock    sync.Mutex
+	maxTtl  time.Duration
+	entries map[string]*cacheEntry
+}
+
+func newCache(maxTtl time.Duration) *cache {
+	return &cache{
+		maxTtl:  maxTtl,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (c *cache) Get(key string) (resp []byte) {
+	if c.maxTtl <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := c.entries[key]
+	if entry == nil {
+		return
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return
+	}
+
+	resp = make([]byte, len(entry.resp))
+	copy(resp, entry.resp)
+
+	return
+}
+
+func (c *cache) Set(key string, resp []byte) {
+	if c.maxTtl <= 0 || isTruncated(resp) {
+		return
+	}
+
+	ttl := c.maxTtl
+	rcode := getRcode(resp)
+	if rcode != 0 && rcode != rcodeName {
+		return
+	}
+
+	rrTtl, ok := getTtl(resp)
+	if rcode != 0 || !ok {
+		ttl = cacheNegTtl
+	} else if time.Duration(rrTtl)*time.Second < ttl {
+		ttl = time.Duration(rrTtl) * time.Second
+	}
+	if ttl < cacheMinTtl {
+		return
+	}
+	if ttl > c.maxTtl {
+		ttl = c.maxTtl
+	}
+
+	entry := &cacheEntry{
+		resp:    make([]byte, len(resp)),
+		expires: time.Now().Add(ttl),
+	}
+	copy(entry.resp, resp)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.entries) >= cacheMax {
+		now := time.Now()
+		for entryKey, ent := range c.entries {
+			if now.After(ent.expires) {
+				delete(c.entries, entryKey)
+			}
+		}
+		if len(c.entries) >= cacheMax {
+			c.entries = map[string]*cacheEntry{}
+		}
+	}
+
+	c.entries[key] = entry
+}
+
+func (c *cache) Clear() {
+	c.lock.Lock()
+	c.entries = map[string]*cacheEntry{}
+	c.lock.Unlock()
+}
@@ -0,0 +1,547 @@
+package dnsstub
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dnsPort      = "53"
+	queryTimeout = 2 * time.Second
+	tcpTimeout   = 10 * time.Second
+)
+
+var (
+	server     *Server
+	serverLock = sync.Mutex{}
+)
+
+// Route sends queries for the domain and its subdomains to the servers,
+// a route without a domain replaces the upstream servers
+type Route struct {
+	Domain  string
+	Servers []string
+}
+
+type Server struct {
+	addr     string
+	udpConn  *net.UDPConn
+	tcpLis   *net.TCPListener
+	cache    *cache
+	lock     sync.RWMutex
+	routes   []*Route
+	upstream []string
+	stop     bool
+}
+
+func normalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func withPort(addr string) string {
+	_, _, err := net.SplitHostPort(addr)
+	if err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), dnsPort)
+}
+
+// getServers returns the servers of the longest matching route or the
+// upstream servers
+func (s *Server) getServers(name string) (servers []string, domain string) {
+	name = normalizeDomain(name)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	servers = s.upstream
+	match := -1
+	for _, route := range s.routes {
+		if route.Domain == "" {
+			if match == -1 {
+				servers = route.Servers
+			}
+			continue
+		}
+
+		if name != route.Domain &&
+			!strings.HasSuffix(name, "."+route.Domain) {
+
+			continue
+		}
+
+		if len(route.Domain) > match {
+			match = len(route.Domain)
+			servers = route.Servers
+			domain = route.Domain
+		}
+	}
+
+	return
+}
+
+func (s *Server) exchangeUdp(srv string, query []byte) (
+	resp []byte, err error) {
+
+	conn, err := net.DialTimeout("udp", withPort(srv), queryTimeout)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dnsstub: Failed to connect to server"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	_, err = conn.Write(query)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dnsstub: Failed to send query"),
+		}
+		return
+	}
+
+	id := getId(query)
+	buf := make([]byte, 65535)
+	for {
+		n, e := conn.Read(buf)
+		if e != nil {
+			err = &errortypes.RequestError{
+				errors.Wrap(e, "dnsstub: Failed to read response"),
+			}
+			return
+		}
+
+		if n < headerLen || getId(buf[:n]) != id {
+			continue
+		}
+
+		resp = make([]byte, n)
+		copy(resp, buf[:n])
+		return
+	}
+}
+
+func (s *Server) exchangeTcp(srv string, query []byte) (
+	resp []byte, err error) {
+
+	conn, err := net.DialTimeout("tcp", withPort(srv), queryTimeout)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dnsstub: Failed to connect to server"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	buf := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(buf, uint16(len(query)))
+	copy(buf[2:], query)
+
+	_, err = conn.Write(buf)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dnsstub: Failed to send query"),
+		}
+		return
+	}
+
+	resp, err = readTcpMsg(conn)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// resolve returns the response to the query from the cache or the servers
+// of the matching route, a server failure is returned when all servers fail
+func (s *Server) resolve(query []byte, tcp bool) (resp []byte) {
+	q, err := parseQuestion(query)
+	if err != nil {
+		return
+	}
+
+	id := getId(query)
+	key := q.Key()
+
+	resp = s.cache.Get(key)
+	if resp != nil {
+		setId(resp, id)
+		return
+	}
+
+	servers, domain := s.getServers(q.Name)
+	for _, srv := range servers {
+		if tcp {
+			resp, err = s.exchangeTcp(srv, query)
+		} else {
+			resp, err = s.exchangeUdp(srv, query)
+		}
+		if err == nil {
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"domain": domain,
+			"server": srv,
+			"error":  err,
+		}).Warn("dnsstub: Failed to forward query")
+	}
+
+	if resp == nil {
+		resp = errorResponse(query, q, rcodeServer)
+		return
+	}
+
+	s.cache.Set(key, resp)
+
+	return
+}
+
+func (s *Server) serveUdp() {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			}).Error("dnsstub: UDP server panic")
+		}
+	}()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udpConn.ReadFromUDP(buf)
+		if err != nil {
+			if s.isStopped() {
+				return
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("dnsstub: Failed to read query")
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		go func() {
+			defer func() {
+				panc := recover()
+				if panc != nil {
+					logrus.WithFields(logrus.Fields{
+						"trace": string(debug.Stack()),
+						"panic": panc,
+					}).Error("dnsstub: UDP query panic")
+				}
+			}()
+
+			resp := s.resolve(query, false)
+			if resp == nil {
+				return
+			}
+
+			_, _ = s.udpConn.WriteToUDP(resp, addr)
+		}()
+	}
+}
+
+func readTcpMsg(conn net.Conn) (msg []byte, err error) {
+	sizeBuf := make([]byte, 2)
+	_, err = io.ReadFull(conn, sizeBuf)
+	if err != nil {
+		return
+	}
+
+	msg = make([]byte, binary.BigEndian.Uint16(sizeBuf))
+	_, err = io.ReadFull(conn, msg)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *Server) handleTcp(conn net.Conn) {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			}).Error("dnsstub: TCP query panic")
+		}
+	}()
+	defer conn.Close()
+
+	for {
+		_ = conn.SetDeadline(time.Now().Add(tcpTimeout))
+
+		query, err := readTcpMsg(conn)
+		if err != nil {
+			return
+		}
+
+		resp := s.resolve(query, true)
+		if resp == nil {
+			return
+		}
+
+		buf := make([]byte, 2+len(resp))
+		binary.BigEndian.PutUint16(buf, uint16(len(resp)))
+		copy(buf[2:], resp)
+
+		_, err = conn.Write(buf)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) serveTcp() {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			}).Error("dnsstub: TCP server panic")
+		}
+	}()
+
+	for {
+		conn, err := s.tcpLis.Accept()
+		if err != nil {
+			if s.isStopped() {
+				return
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("dnsstub: Failed to accept connection")
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		go s.handleTcp(conn)
+	}
+}
+
+func (s *Server) isStopped() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.stop
+}
+
+// Start listens on the loopback address for UDP and TCP queries
+func Start(addr string, cacheTtl time.Duration) (err error) {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+
+	if server != nil {
+		return
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dnsstub: Failed to parse address"),
+		}
+		return
+	}
+
+	if udpAddr.IP == nil || !udpAddr.IP.IsLoopback() {
+		err = &errortypes.ParseError{
+			errors.Newf("dnsstub: Address '%s' is not loopback", addr),
+		}
+		return
+	}
+
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dnsstub: Failed to listen on UDP"),
+		}
+		return
+	}
+
+	tcpLis, err := net.ListenTCP("tcp", &net.TCPAddr{
+		IP:   udpAddr.IP,
+		Port: udpAddr.Port,
+	})
+	if err != nil {
+		udpConn.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "dnsstub: Failed to listen on TCP"),
+		}
+		return
+	}
+
+	server = &Server{
+		addr:     udpAddr.String(),
+		udpConn:  udpConn,
+		tcpLis:   tcpLis,
+		cache:    newCache(cacheTtl),
+		routes:   []*Route{},
+		upstream: []string{},
+	}
+
+	go server.serveUdp()
+	go server.serveTcp()
+
+	logrus.WithFields(logrus.Fields{
+		"address": server.addr,
+	}).Info("dnsstub: Started DNS stub resolver")
+
+	return
+}
+
+func Stop() {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	server.lock.Lock()
+	server.stop = true
+	server.lock.Unlock()
+
+	server.udpConn.Close()
+	server.tcpLis.Close()
+	server = nil
+}
+
+func getServer() *Server {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+
+	return server
+}
+
+func IsRunning() bool {
+	return getServer() != nil
+}
+
+// GetHost returns the address of the stub without the port
+func GetHost() (host string) {
+	srv := getServer()
+	if srv == nil {
+		return
+	}
+
+	host, _, _ = net.SplitHostPort(srv.addr)
+	return
+}
+
+func routesEqual(a, b []*Route) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Domain != b[i].Domain ||
+			strings.Join(a[i].Servers, ",") !=
+				strings.Join(b[i].Servers, ",") {
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// Update replaces the routes, the cache is cleared when the routes change
+func Update(routes []*Route) {
+	srv := getServer()
+	if srv == nil {
+		return
+	}
+
+	newRoutes := []*Route{}
+	for _, route := range routes {
+		if len(route.Servers) == 0 {
+			continue
+		}
+
+		newRoutes = append(newRoutes, &Route{
+			Domain:  normalizeDomain(route.Domain),
+			Servers: route.Servers,
+		})
+	}
+
+	sort.SliceStable(newRoutes, func(i, j int) bool {
+		return newRoutes[i].Domain < newRoutes[j].Domain
+	})
+
+	srv.lock.Lock()
+	changed := !routesEqual(srv.routes, newRoutes)
+	srv.routes = newRoutes
+	srv.lock.Unlock()
+
+	if changed {
+		srv.cache.Clear()
+
+		domains := []string{}
+		for _, route := range newRoutes {
+			domains = append(domains, route.Domain)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"domains": domains,
+		}).Info("dnsstub: Updated DNS stub routes")
+	}
+}
+
+// SetUpstream replaces the servers used for queries without a matching
+// route, the address of the stub is ignored to prevent loops
+func SetUpstream(servers []string) {
+	srv := getServer()
+	if srv == nil {
+		return
+	}
+
+	host, _, _ := net.SplitHostPort(srv.addr)
+
+	upstream := []string{}
+	for _, addr := range servers {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.String() == host ||
+			ip.String() == "127.0.0.53" || ip.String() == "127.0.0.54" {
+
+			continue
+		}
+		upstream = append(upstream, ip.String())
+	}
+
+	srv.lock.Lock()
+	changed := strings.Join(srv.upstream, ",") != strings.Join(upstream, ",")
+	srv.upstream = upstream
+	srv.lock.Unlock()
+
+	if changed {
+		srv.cache.Clear()
+
+		logrus.WithFields(logrus.Fields{
+			"servers": upstream,
+		}).Info("dnsstub: Updated DNS stub upstream servers")
+	}
+}
@@ -0,0 +1,216 @@
+package dnsstub
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+)
+
+const (
+	headerLen   = 12
+	rcodeMask   = 0x000f
+	flagQr      = 0x8000
+	flagTc      = 0x0200
+	rcodeServer = 2
+	rcodeName   = 3
+)
+
+type question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	end   int
+}
+
+func (q *question) Key() string {
+	return fmt.Sprintf("%s|%d|%d",
+		strings.ToLower(q.Name), q.Type, q.Class)
+}
+
+// readName reads the domain name at the offset and returns the offset
+// after the name, compression pointers are followed
+func readName(msg []byte, off int) (name string, next int, err error) {
+	labels := []string{}
+	next = -1
+	jumps := 0
+
+	for {
+		if off >= len(msg) {
+			err = &errortypes.ParseError{
+				errors.New("dnsstub: Name exceeds message"),
+			}
+			return
+		}
+
+		size := int(msg[off])
+		switch size & 0xc0 {
+		case 0x00:
+			if size == 0 {
+				if next == -1 {
+					next = off + 1
+				}
+				name = strings.Join(labels, ".")
+				return
+			}
+
+			if off+1+size > len(msg) {
+				err = &errortypes.ParseError{
+					errors.New("dnsstub: Label exceeds message"),
+				}
+				return
+			}
+
+			labels = append(labels, string(msg[off+1:off+1+size]))
+			off += 1 + size
+		case 0xc0:
+			if off+1 >= len(msg) {
+				err = &errortypes.ParseError{
+					errors.New("dnsstub: Pointer exceeds message"),
+				}
+				return
+			}
+
+			jumps += 1
+			if jumps > 32 {
+				err = &errortypes.ParseError{
+					errors.New("dnsstub: Too many name pointers"),
+				}
+				return
+			}
+
+			if next == -1 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			err = &errortypes.ParseError{
+				errors.New("dnsstub: Invalid label type"),
+			}
+			return
+		}
+	}
+}
+
+// parseQuestion returns the first question of the message
+func parseQuestion(msg []byte) (q *question, err error) {
+	if len(msg) < headerLen {
+		err = &errortypes.ParseError{
+			errors.New("dnsstub: Message too short"),
+		}
+		return
+	}
+
+	if binary.BigEndian.Uint16(msg[4:]) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("dnsstub: Message missing question"),
+		}
+		return
+	}
+
+	name, off, err := readName(msg, headerLen)
+	if err != nil {
+		return
+	}
+
+	if off+4 > len(msg) {
+		err = &errortypes.ParseError{
+			errors.New("dnsstub: Question exceeds message"),
+		}
+		return
+	}
+
+	q = &question{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[off:]),
+		Class: binary.BigEndian.Uint16(msg[off+2:]),
+		end:   off + 4,
+	}
+
+	return
+}
+
+func getId(msg []byte) uint16 {
+	return binary.BigEndian.Uint16(msg)
+}
+
+func setId(msg []byte, id uint16) {
+	binary.BigEndian.PutUint16(msg, id)
+}
+
+func getRcode(msg []byte) int {
+	return int(binary.BigEndian.Uint16(msg[2:]) & rcodeMask)
+}
+
+func isTruncated(msg []byte) bool {
+	return binary.BigEndian.Uint16(msg[2:])&flagTc != 0
+}
+
+// getTtl returns the lowest ttl of the answer and authority records
+func getTtl(msg []byte) (ttl uint32, ok bool) {
+	if len(msg) < headerLen {
+		return
+	}
+
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	rrCount := int(binary.BigEndian.Uint16(msg[6:])) +
+		int(binary.BigEndian.Uint16(msg[8:]))
+
+	off := headerLen
+	for i := 0; i < qdCount; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return
+		}
+		off = next + 4
+	}
+
+	found := false
+	for i := 0; i < rrCount; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return
+		}
+		off = next
+
+		if off+10 > len(msg) {
+			return
+		}
+
+		rrTtl := binary.BigEndian.Uint32(msg[off+4:])
+		size := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10 + size
+		if off > len(msg) {
+			return
+		}
+
+		if !found || rrTtl < ttl {
+			ttl = rrTtl
+			found = true
+		}
+	}
+
+	ok = found
+	return
+}
+
+// errorResponse returns a response to the query with the question and the
+// response code
+func errorResponse(query []byte, q *question, rcode int) (resp []byte) {
+	resp = make([]byte, q.end)
+	copy(resp, query[:q.end])
+
+	flags := binary.BigEndian.Uint16(resp[2:])
+	flags = (flags | flagQr) &^ rcodeMask &^ flagTc
+	flags |= uint16(rcode)
+	binary.BigEndian.PutUint16(resp[2:], flags)
+
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], 0)
+	binary.BigEndian.PutUint16(resp[8:], 0)
+	binary.BigEndian.PutUint16(resp[10:], 0)
+
+	return
+}
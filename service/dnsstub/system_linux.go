@@ -0,0 +1,61 @@
+package dnsstub
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+)
+
+const (
+	Supported      = true
+	DefaultAddress = "127.0.0.153:53"
+)
+
+func readResolvConf(pth string) (servers []string, err error) {
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "dnsstub: Failed to read resolv.conf"),
+		}
+		return
+	}
+
+	servers = []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+
+		ip := net.ParseIP(fields[1])
+		if ip != nil {
+			servers = append(servers, ip.String())
+		}
+	}
+
+	return
+}
+
+// GetSystemServers returns the system DNS servers, the upstream servers of
+// systemd-resolved are used when resolv.conf points to the resolved stub
+func GetSystemServers() (servers []string, err error) {
+	servers, err = readResolvConf("/etc/resolv.conf")
+	if err != nil {
+		return
+	}
+
+	for _, server := range servers {
+		if server == "127.0.0.53" || server == "127.0.0.54" {
+			servers, err = readResolvConf("/run/systemd/resolve/resolv.conf")
+			if err != nil {
+				return
+			}
+			break
+		}
+	}
+
+	return
+}
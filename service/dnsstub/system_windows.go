@@ -0,0 +1,11 @@
+package dnsstub
+
+const (
+	Supported      = false
+	DefaultAddress = "127.0.0.1:53"
+)
+
+func GetSystemServers() (servers []string, err error) {
+	servers = []string{}
+	return
+}
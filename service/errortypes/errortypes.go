@@ -31,3 +31,7 @@ type ExecError struct {
 type RequestError struct {
 	errors.DropboxError
 }
+
+type VerificationError struct {
+	errors.DropboxError
+}
@@ -0,0 +1,184 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bufferSize       = 512
+	subscriptionSize = 64
+)
+
+var buffer = &ringBuffer{
+	epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+	records: make([]*Record, bufferSize),
+	subs:    map[*Subscription]bool{},
+}
+
+// Record is a serialized event retained for replay. The data is marshaled
+// when the event is emitted so replayed events reflect the state at the
+// time they occurred.
+type Record struct {
+	Seq  uint64
+	Id   string
+	Type string
+	Data []byte
+}
+
+type ringBuffer struct {
+	lock    sync.Mutex
+	epoch   string
+	seq     uint64
+	records []*Record
+	subs    map[*Subscription]bool
+}
+
+// StreamId returns the stream id of the record, the id is prefixed with
+// the epoch of the service run because the sequence restarts with the
+// service
+func (r *Record) StreamId() string {
+	return fmt.Sprintf("%s-%d", buffer.epoch, r.Seq)
+}
+
+// ParseStreamId returns the epoch and sequence of a stream id
+func ParseStreamId(id string) (epoch string, seq uint64) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return
+	}
+
+	index := strings.LastIndex(id, "-")
+	if index == -1 {
+		seq, _ = strconv.ParseUint(id, 10, 64)
+		return
+	}
+
+	epoch = id[:index]
+	seq, _ = strconv.ParseUint(id[index+1:], 10, 64)
+
+	return
+}
+
+type Subscription struct {
+	stream chan *Record
+	closed bool
+}
+
+func (s *Subscription) Listen() chan *Record {
+	return s.stream
+}
+
+func (s *Subscription) Close() {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.closeSub(s)
+}
+
+func (b *ringBuffer) closeSub(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+
+	delete(b.subs, sub)
+	close(sub.stream)
+}
+
+func (b *ringBuffer) push(evt *Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.seq += 1
+	evt.Seq = b.seq
+
+	data, err := json.Marshal(evt)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"type":  evt.Type,
+			"error": err,
+		}).Error("event: Failed to marshal event")
+		data = nil
+	}
+
+	record := &Record{
+		Seq:  evt.Seq,
+		Id:   evt.Id,
+		Type: evt.Type,
+		Data: data,
+	}
+	b.records[record.Seq%bufferSize] = record
+
+	for sub := range b.subs {
+		select {
+		case sub.stream <- record:
+		default:
+			// Subscriber fell behind, close it so the client reconnects
+			// and recovers the missed events from the buffer
+			logrus.Warn("event: Subscription overflow, closing stream")
+			b.closeSub(sub)
+		}
+	}
+}
+
+// Subscribe registers a new ordered event stream and returns the buffered
+// events after lastSeq. Lost is set when events after lastSeq have already
+// been evicted from the buffer or when the epoch is from a previous run of
+// the service, in which case all buffered events are returned.
+func Subscribe(epoch string, lastSeq uint64) (sub *Subscription,
+	missed []*Record, lost bool) {
+
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	sub = &Subscription{
+		stream: make(chan *Record, subscriptionSize),
+	}
+	buffer.subs[sub] = true
+
+	missed = []*Record{}
+
+	if epoch == "" && lastSeq == 0 {
+		return
+	}
+
+	if epoch != buffer.epoch || lastSeq > buffer.seq {
+		// Sequence restarted with the service, the client missed the
+		// events of the previous service and all events since the restart
+		lost = true
+		lastSeq = 0
+	} else if lastSeq == buffer.seq {
+		return
+	}
+
+	start := lastSeq + 1
+	if buffer.seq-lastSeq > bufferSize {
+		start = buffer.seq - bufferSize + 1
+		lost = true
+	}
+
+	for seq := start; seq <= buffer.seq; seq++ {
+		record := buffer.records[seq%bufferSize]
+		if record == nil || record.Seq != seq {
+			lost = true
+			continue
+		}
+		missed = append(missed, record)
+	}
+
+	return
+}
+
+func GetSeq() uint64 {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	return buffer.seq
+}
@@ -0,0 +1,244 @@
+package event
+
+import (
+	"testing"
+)
+
+const testEpoch = "epoch"
+
+func resetBuffer(count int) {
+	buffer = &ringBuffer{
+		epoch:   testEpoch,
+		records: make([]*Record, bufferSize),
+		subs:    map[*Subscription]bool{},
+	}
+
+	for i := 0; i < count; i++ {
+		buffer.push(&Event{
+			Type: "test",
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		pushed   int
+		epoch    string
+		lastSeq  uint64
+		firstSeq uint64
+		count    int
+		lost     bool
+	}{
+		{
+			name:    "new_client",
+			pushed:  5,
+			epoch:   "",
+			lastSeq: 0,
+			count:   0,
+			lost:    false,
+		},
+		{
+			name:    "current",
+			pushed:  5,
+			epoch:   testEpoch,
+			lastSeq: 5,
+			count:   0,
+			lost:    false,
+		},
+		{
+			name:     "partial",
+			pushed:   5,
+			epoch:    testEpoch,
+			lastSeq:  2,
+			firstSeq: 3,
+			count:    3,
+			lost:     false,
+		},
+		{
+			name:     "epoch_start",
+			pushed:   5,
+			epoch:    testEpoch,
+			lastSeq:  0,
+			firstSeq: 1,
+			count:    5,
+			lost:     false,
+		},
+		{
+			name:     "previous_epoch",
+			pushed:   5,
+			epoch:    "previous",
+			lastSeq:  2,
+			firstSeq: 1,
+			count:    5,
+			lost:     true,
+		},
+		{
+			name:     "missing_epoch",
+			pushed:   5,
+			epoch:    "",
+			lastSeq:  2,
+			firstSeq: 1,
+			count:    5,
+			lost:     true,
+		},
+		{
+			name:     "seq_ahead",
+			pushed:   5,
+			epoch:    testEpoch,
+			lastSeq:  10,
+			firstSeq: 1,
+			count:    5,
+			lost:     true,
+		},
+		{
+			name:     "evicted",
+			pushed:   bufferSize + 10,
+			epoch:    testEpoch,
+			lastSeq:  1,
+			firstSeq: 11,
+			count:    bufferSize,
+			lost:     true,
+		},
+		{
+			name:     "not_evicted",
+			pushed:   bufferSize + 10,
+			epoch:    testEpoch,
+			lastSeq:  10,
+			firstSeq: 11,
+			count:    bufferSize,
+			lost:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetBuffer(test.pushed)
+
+			sub, missed, lost := Subscribe(test.epoch, test.lastSeq)
+			defer sub.Close()
+
+			if lost != test.lost {
+				t.Errorf("lost %t, expected %t", lost, test.lost)
+			}
+			if len(missed) != test.count {
+				t.Fatalf("missed %d, expected %d", len(missed), test.count)
+			}
+
+			for i, record := range missed {
+				expected := test.firstSeq + uint64(i)
+				if record.Seq != expected {
+					t.Fatalf("record seq %d, expected %d",
+						record.Seq, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeStream(t *testing.T) {
+	resetBuffer(0)
+
+	sub, _, _ := Subscribe("", 0)
+	defer sub.Close()
+
+	buffer.push(&Event{
+		Type: "test",
+	})
+
+	record := <-sub.Listen()
+	if record.Seq != 1 || record.Type != "test" {
+		t.Errorf("record seq %d type %s, expected 1 test",
+			record.Seq, record.Type)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	resetBuffer(0)
+
+	sub, _, _ := Subscribe("", 0)
+
+	for i := 0; i < subscriptionSize+1; i++ {
+		buffer.push(&Event{
+			Type: "test",
+		})
+	}
+
+	if !sub.closed {
+		t.Fatalf("subscription not closed on overflow")
+	}
+
+	count := 0
+	for range sub.Listen() {
+		count += 1
+	}
+	if count != subscriptionSize {
+		t.Errorf("received %d, expected %d", count, subscriptionSize)
+	}
+
+	sub.Close()
+}
+
+func TestParseStreamId(t *testing.T) {
+	tests := []struct {
+		id    string
+		epoch string
+		seq   uint64
+	}{
+		{
+			id:    "",
+			epoch: "",
+			seq:   0,
+		},
+		{
+			id:    "42",
+			epoch: "",
+			seq:   42,
+		},
+		{
+			id:    "abc-42",
+			epoch: "abc",
+			seq:   42,
+		},
+		{
+			id:    " abc-42 ",
+			epoch: "abc",
+			seq:   42,
+		},
+		{
+			id:    "a-b-7",
+			epoch: "a-b",
+			seq:   7,
+		},
+		{
+			id:    "abc-x",
+			epoch: "abc",
+			seq:   0,
+		},
+		{
+			id:    "invalid",
+			epoch: "",
+			seq:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			epoch, seq := ParseStreamId(test.id)
+			if epoch != test.epoch || seq != test.seq {
+				t.Errorf("parsed %q %d, expected %q %d",
+					epoch, seq, test.epoch, test.seq)
+			}
+		})
+	}
+}
+
+func TestStreamIdRoundTrip(t *testing.T) {
+	resetBuffer(3)
+
+	record := buffer.records[3]
+	epoch, seq := ParseStreamId(record.StreamId())
+	if epoch != testEpoch || seq != 3 {
+		t.Errorf("parsed %q %d, expected %q 3", epoch, seq, testEpoch)
+	}
+}
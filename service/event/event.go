@@ -21,6 +21,7 @@ var (
 
 type Event struct {
 	Id   string      `json:"id"`
+	Seq  uint64      `json:"seq"`
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
 }
@@ -28,6 +29,8 @@ type Event struct {
 func (e *Event) Init() {
 	e.Id = utils.Uuid()
 
+	buffer.push(e)
+
 	listeners.RLock()
 	defer listeners.RUnlock()
 
@@ -12,11 +12,13 @@ import (
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
 	"github.com/sirupsen/logrus"
 )
 
 var (
 	clientTransport = &http.Transport{
+		Proxy:               proxy.Func,
 		DisableKeepAlives:   true,
 		TLSHandshakeTimeout: 10 * time.Second,
 		TLSClientConfig: &tls.Config{
@@ -0,0 +1,244 @@
+package geosort
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
+	"github.com/pritunl/pritunl-client-electron/service/resolver"
+	"github.com/pritunl/pritunl-client-electron/service/types"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	latencyCache     = map[string]*latencyResult{}
+	latencyLock      = sync.Mutex{}
+	latencyTransport = &http.Transport{
+		Proxy:             proxy.Func,
+		DialContext:       resolver.DialContext,
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+			MaxVersion:         tls.VersionTLS13,
+		},
+	}
+	latencyClient = &http.Client{
+		Transport: latencyTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+type latencyResult struct {
+	Addr      string
+	Latency   time.Duration
+	Failed    bool
+	Timestamp time.Time
+}
+
+func getDialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = strings.Trim(addr, "[]")
+		port = "443"
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// getCacheKey returns the cache key for the remote, results are only
+// shared between profiles that reach the remote through the same proxy
+func getCacheKey(prxy *types.Proxy, addr string) string {
+	proxyUrl := ""
+	u, err := proxy.GetUrl(prxy, getDialAddr(addr))
+	if err == nil && u != nil {
+		proxyUrl = u.String()
+	}
+
+	return addr + "|" + proxyUrl
+}
+
+// measureLatency returns the time to open a TCP connection and complete
+// the TLS handshake with the web port of the remote, the connection uses
+// the same proxy and resolver as the connection
+func measureLatency(prxy *types.Proxy, addr string, timeout time.Duration) (
+	latency time.Duration, err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	u := &url.URL{
+		Scheme: "https",
+		Host:   getDialAddr(addr),
+		Path:   "/",
+	}
+
+	var connectStart time.Time
+	var connectTime time.Duration
+	var tlsStart time.Time
+	var tlsTime time.Duration
+	var tlsDone bool
+	traceLock := sync.Mutex{}
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			traceLock.Lock()
+			connectStart = time.Now()
+			traceLock.Unlock()
+		},
+		ConnectDone: func(network, addr string, e error) {
+			traceLock.Lock()
+			if e == nil {
+				connectTime = time.Since(connectStart)
+			}
+			traceLock.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			traceLock.Lock()
+			tlsStart = time.Now()
+			traceLock.Unlock()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, e error) {
+			traceLock.Lock()
+			if e == nil {
+				tlsTime = time.Since(tlsStart)
+				tlsDone = true
+			}
+			traceLock.Unlock()
+			if e == nil {
+				cancel()
+			}
+		},
+	}
+
+	req, err := http.NewRequestWithContext(
+		httptrace.WithClientTrace(proxy.WithProfile(ctx, prxy), trace),
+		"HEAD",
+		u.String(),
+		nil,
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "geosort: Failed to create request"),
+		}
+		return
+	}
+
+	// The request is canceled once the handshake is complete, only the
+	// connection setup is measured
+	resp, err := latencyClient.Do(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	traceLock.Lock()
+	defer traceLock.Unlock()
+
+	if !tlsDone {
+		if err == nil {
+			err = errors.New("geosort: Handshake did not complete")
+		}
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "geosort: Failed to connect to remote"),
+		}
+		return
+	}
+	err = nil
+
+	latency = connectTime + tlsTime
+
+	return
+}
+
+// LatencySort orders the remote addresses by the measured latency, all
+// remotes are measured in parallel within the timeout. Results are cached
+// for the cache ttl, failures are not cached and remotes that failed are
+// placed last in the input order.
+func LatencySort(prxy *types.Proxy, addrs []string,
+	timeout, cacheTtl time.Duration) (newAddrs []string) {
+
+	results := make([]*latencyResult, len(addrs))
+	waiter := sync.WaitGroup{}
+
+	for i, addr := range addrs {
+		key := getCacheKey(prxy, addr)
+
+		latencyLock.Lock()
+		cached := latencyCache[key]
+		latencyLock.Unlock()
+
+		if cached != nil && time.Since(cached.Timestamp) < cacheTtl {
+			results[i] = cached
+			continue
+		}
+
+		waiter.Add(1)
+		go func(i int, addr, key string) {
+			defer waiter.Done()
+
+			result := &latencyResult{
+				Addr:      addr,
+				Timestamp: time.Now(),
+			}
+
+			latency, err := measureLatency(prxy, addr, timeout)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"remote": addr,
+					"error":  err,
+				}).Info("geosort: Failed to measure remote latency")
+
+				result.Failed = true
+			} else {
+				result.Latency = latency
+
+				latencyLock.Lock()
+				latencyCache[key] = result
+				latencyLock.Unlock()
+			}
+
+			results[i] = result
+		}(i, addr, key)
+	}
+
+	waiter.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Failed != results[j].Failed {
+			return !results[i].Failed
+		}
+		if results[i].Failed {
+			return false
+		}
+		return results[i].Latency < results[j].Latency
+	})
+
+	newAddrs = []string{}
+	latencies := logrus.Fields{}
+	for _, result := range results {
+		newAddrs = append(newAddrs, result.Addr)
+		if result.Failed {
+			latencies[result.Addr] = "failed"
+		} else {
+			latencies[result.Addr] = result.Latency.String()
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"latencies": latencies,
+	}).Info("geosort: Latency sort complete")
+
+	return
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"os/user"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-client-electron/service/auth"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/peer"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ReadPerm    = "read"
+	ControlPerm = "control"
+	ConfigPerm  = "config"
+	NetworkPerm = "network"
+	AdminPerm   = "admin"
+)
+
+func getTokenScope(perm string) string {
+	switch perm {
+	case ReadPerm:
+		return auth.ReadScope
+	case ControlPerm:
+		return auth.ProfileControlScope
+	case ConfigPerm:
+		return auth.ConfigScope
+	}
+
+	return auth.AdminScope
+}
+
+func getAccessRule(policy *config.AccessPolicy,
+	perm string) *config.AccessRule {
+
+	switch perm {
+	case ReadPerm:
+		return policy.Read
+	case ControlPerm:
+		return policy.Control
+	case ConfigPerm:
+		return policy.Config
+	case NetworkPerm:
+		return policy.Network
+	case AdminPerm:
+		return policy.Admin
+	}
+
+	return nil
+}
+
+func checkAccess(policy *config.AccessPolicy, creds *peer.Creds,
+	perm string) bool {
+
+	if creds.Uid == 0 {
+		return true
+	}
+
+	rule := getAccessRule(policy, perm)
+	if rule == nil {
+		return false
+	}
+
+	for _, uid := range rule.Uids {
+		if uid == creds.Uid {
+			return true
+		}
+	}
+
+	if len(rule.Groups) == 0 {
+		return false
+	}
+
+	gids := creds.Groups()
+	for _, group := range rule.Groups {
+		gid, err := strconv.Atoi(group)
+		if err != nil {
+			grp, e := user.LookupGroup(group)
+			if e != nil {
+				continue
+			}
+
+			gid, err = strconv.Atoi(grp.Gid)
+			if err != nil {
+				continue
+			}
+		}
+
+		if gids.Contains(gid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Credentials(c *gin.Context) {
+	creds := peer.FromContext(c.Request.Context())
+	if creds != nil {
+		c.Set("peer_pid", creds.Pid)
+		c.Set("peer_uid", creds.Uid)
+		c.Set("peer_gid", creds.Gid)
+	}
+
+	c.Next()
+}
+
+func Access(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenInf, ok := c.Get("api_token"); ok {
+			tokn := tokenInf.(*auth.Token)
+			scope := getTokenScope(perm)
+
+			if !tokn.HasScope(scope) {
+				logrus.WithFields(logrus.Fields{
+					"path":     c.Request.URL.Path,
+					"token_id": tokn.Id,
+					"scope":    scope,
+				}).Warn("handlers: Token scope not permitted")
+
+				c.AbortWithStatus(403)
+				return
+			}
+		}
+
+		policy := config.GetAccessPolicy()
+		if policy == nil || !peer.Supported {
+			c.Next()
+			return
+		}
+
+		creds := peer.FromContext(c.Request.Context())
+		if creds == nil {
+			if peer.IsSocket(c.Request.Context()) {
+
+				logrus.WithFields(logrus.Fields{
+					"path":       c.Request.URL.Path,
+					"permission": perm,
+				}).Warn("handlers: Missing peer credentials")
+
+				c.AbortWithStatus(403)
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		if !checkAccess(policy, creds, perm) {
+			logrus.WithFields(logrus.Fields{
+				"path":       c.Request.URL.Path,
+				"permission": perm,
+				"uid":        creds.Uid,
+				"gid":        creds.Gid,
+				"pid":        creds.Pid,
+			}).Warn("handlers: Caller not permitted")
+
+			c.AbortWithStatus(403)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/pritunl/pritunl-client-electron/service/auth"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/peer"
+)
+
+// Unassigned ids so the group lookup only returns the primary group
+const (
+	testUid = 54321
+	testGid = 54322
+)
+
+func TestGetAccessRule(t *testing.T) {
+	policy := &config.AccessPolicy{
+		Read:    &config.AccessRule{},
+		Control: &config.AccessRule{},
+		Config:  &config.AccessRule{},
+		Network: &config.AccessRule{},
+		Admin:   &config.AccessRule{},
+	}
+
+	tests := []struct {
+		perm     string
+		expected *config.AccessRule
+	}{
+		{
+			perm:     ReadPerm,
+			expected: policy.Read,
+		},
+		{
+			perm:     ControlPerm,
+			expected: policy.Control,
+		},
+		{
+			perm:     ConfigPerm,
+			expected: policy.Config,
+		},
+		{
+			perm:     NetworkPerm,
+			expected: policy.Network,
+		},
+		{
+			perm:     AdminPerm,
+			expected: policy.Admin,
+		},
+		{
+			perm:     "unknown",
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.perm, func(t *testing.T) {
+			rule := getAccessRule(policy, test.perm)
+			if rule != test.expected {
+				t.Errorf("rule %p, expected %p", rule, test.expected)
+			}
+		})
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *config.AccessPolicy
+		creds    *peer.Creds
+		perm     string
+		expected bool
+	}{
+		{
+			name:   "root",
+			policy: &config.AccessPolicy{},
+			creds: &peer.Creds{
+				Uid: 0,
+				Gid: 0,
+			},
+			perm:     AdminPerm,
+			expected: true,
+		},
+		{
+			name:   "no_rule",
+			policy: &config.AccessPolicy{},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ReadPerm,
+			expected: false,
+		},
+		{
+			name: "uid_allowed",
+			policy: &config.AccessPolicy{
+				Read: &config.AccessRule{
+					Uids: []int{testUid},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ReadPerm,
+			expected: true,
+		},
+		{
+			name: "uid_other_perm",
+			policy: &config.AccessPolicy{
+				Read: &config.AccessRule{
+					Uids: []int{testUid},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ControlPerm,
+			expected: false,
+		},
+		{
+			name: "uid_denied",
+			policy: &config.AccessPolicy{
+				Control: &config.AccessRule{
+					Uids: []int{testUid + 10},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ControlPerm,
+			expected: false,
+		},
+		{
+			name: "gid_allowed",
+			policy: &config.AccessPolicy{
+				Config: &config.AccessRule{
+					Groups: []string{"54322"},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ConfigPerm,
+			expected: true,
+		},
+		{
+			name: "gid_denied",
+			policy: &config.AccessPolicy{
+				Config: &config.AccessRule{
+					Groups: []string{"54323"},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     ConfigPerm,
+			expected: false,
+		},
+		{
+			name: "unknown_group",
+			policy: &config.AccessPolicy{
+				Network: &config.AccessRule{
+					Groups: []string{"pritunl-test-missing"},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     NetworkPerm,
+			expected: false,
+		},
+		{
+			name: "admin_rule",
+			policy: &config.AccessPolicy{
+				Admin: &config.AccessRule{
+					Uids: []int{testUid},
+				},
+			},
+			creds: &peer.Creds{
+				Uid: testUid,
+				Gid: testGid,
+			},
+			perm:     AdminPerm,
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allowed := checkAccess(test.policy, test.creds, test.perm)
+			if allowed != test.expected {
+				t.Errorf("access %t, expected %t", allowed, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetTokenScope(t *testing.T) {
+	tests := []struct {
+		perm     string
+		expected string
+	}{
+		{
+			perm:     ReadPerm,
+			expected: auth.ReadScope,
+		},
+		{
+			perm:     ControlPerm,
+			expected: auth.ProfileControlScope,
+		},
+		{
+			perm:     ConfigPerm,
+			expected: auth.ConfigScope,
+		},
+		{
+			perm:     NetworkPerm,
+			expected: auth.AdminScope,
+		},
+		{
+			perm:     AdminPerm,
+			expected: auth.AdminScope,
+		},
+		{
+			perm:     "unknown",
+			expected: auth.AdminScope,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.perm, func(t *testing.T) {
+			scope := getTokenScope(test.perm)
+			if scope != test.expected {
+				t.Errorf("scope %s, expected %s", scope, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-client-electron/service/auth"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+type apiTokenData struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func apiTokensGet(c *gin.Context) {
+	c.JSON(200, auth.GetTokens())
+}
+
+func apiTokenPost(c *gin.Context) {
+	data := &apiTokenData{}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	name := data.Name
+	if len(name) > 128 {
+		name = name[:128]
+	}
+
+	tokn, err := auth.CreateToken(name, data.Scopes)
+	if err != nil {
+		if _, ok := err.(*auth.ParseError); ok {
+			utils.AbortWithError(c, 400, err)
+		} else {
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	c.JSON(200, tokn)
+}
+
+func apiTokenDelete(c *gin.Context) {
+	tokenId := utils.FilterStr(c.Param("token_id"))
+
+	found, err := auth.RevokeToken(tokenId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !found {
+		utils.AbortWithStatus(c, 404)
+		return
+	}
+
+	c.JSON(200, nil)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"runtime/debug"
@@ -16,9 +17,10 @@ import (
 )
 
 const (
-	writeTimeout = 10 * time.Second
-	pingInterval = 30 * time.Second
-	pingWait     = 40 * time.Second
+	writeTimeout   = 10 * time.Second
+	pingInterval   = 30 * time.Second
+	pingWait       = 40 * time.Second
+	streamInterval = 15 * time.Second
 )
 
 var (
@@ -115,3 +117,86 @@ func eventsGet(c *gin.Context) {
 		}
 	}
 }
+
+func writeStreamRecord(c *gin.Context, record *event.Record) (err error) {
+	data := record.Data
+	if data == nil {
+		data = []byte("{}")
+	}
+
+	_, err = fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n",
+		record.StreamId(), record.Type, data)
+	if err != nil {
+		return
+	}
+
+	c.Writer.Flush()
+
+	return
+}
+
+func eventsStreamGet(c *gin.Context) {
+	lastEventId := c.Request.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("last_event_id")
+	}
+
+	epoch, lastSeq := event.ParseStreamId(lastEventId)
+
+	sub, missed, lost := event.Subscribe(epoch, lastSeq)
+	defer sub.Close()
+
+	ctrl := http.NewResponseController(c.Writer)
+	_ = ctrl.SetWriteDeadline(time.Time{})
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.WriteHeader(200)
+
+	_, err := fmt.Fprintf(c.Writer, "retry: 1000\n\n")
+	if err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	if lost {
+		_, err = fmt.Fprintf(c.Writer, "event: events_lost\ndata: {}\n\n")
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+
+	for _, record := range missed {
+		err = writeStreamRecord(c, record)
+		if err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case record, ok := <-sub.Listen():
+			if !ok {
+				return
+			}
+
+			err = writeStreamRecord(c, record)
+			if err != nil {
+				return
+			}
+		case <-ticker.C:
+			_, err = fmt.Fprintf(c.Writer, ": ping\n\n")
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
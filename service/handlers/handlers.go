@@ -44,51 +44,76 @@ func Auth(c *gin.Context) {
 
 	if c.Request.Header.Get("Origin") != "" ||
 		c.Request.Header.Get("Referer") != "" ||
-		c.Request.Header.Get("User-Agent") != "pritunl" ||
-		subtle.ConstantTimeCompare([]byte(token), []byte(auth.Key)) != 1 {
+		c.Request.Header.Get("User-Agent") != "pritunl" {
 
 		c.AbortWithStatus(401)
 		return
 	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(auth.Key)) != 1 {
+		tokn := auth.ValidateToken(token)
+		if tokn == nil {
+			c.AbortWithStatus(401)
+			return
+		}
+		c.Set("api_token", tokn)
+	}
+
 	c.Next()
 }
 
 func Register(engine *gin.Engine) {
 	engine.Use(Auth)
+	engine.Use(Credentials)
 	engine.Use(Recovery)
 	engine.Use(Errors)
 
-	engine.GET("/events", eventsGet)
-	engine.GET("/config", configGet)
-	engine.PUT("/config", configPut)
-	engine.POST("/network/reset_dns", networkDnsReset)
-	engine.POST("/network/reset_all", networkAllReset)
-	engine.POST("/reset_enclave", resetEnclave)
-	engine.GET("/profile", profilesGet)
-	engine.GET("/profile/:profile_id", profileGet)
-	engine.POST("/profile", profilePost)
-	engine.DELETE("/profile", profileDel)
-	engine.DELETE("/profile/:profile_id", profileDel2)
-	engine.GET("/sprofile", sprofilesGet)
-	engine.GET("/sprofile/:profile_id", sprofileGet)
-	engine.PUT("/sprofile", sprofilePut)
-	engine.DELETE("/sprofile", sprofileDel)
-	engine.DELETE("/sprofile/:profile_id", sprofileDel2)
+	read := Access(ReadPerm)
+	control := Access(ControlPerm)
+	conf := Access(ConfigPerm)
+	network := Access(NetworkPerm)
+	admin := Access(AdminPerm)
+
+	engine.GET("/auth/token", admin, apiTokensGet)
+	engine.POST("/auth/token", admin, apiTokenPost)
+	engine.DELETE("/auth/token/:token_id", admin, apiTokenDelete)
+	engine.GET("/events", read, eventsGet)
+	engine.GET("/events/stream", read, eventsStreamGet)
+	engine.GET("/config", read, configGet)
+	engine.PUT("/config", conf, configPut)
+	engine.POST("/network/reset_dns", network, networkDnsReset)
+	engine.POST("/network/reset_all", network, networkAllReset)
+	engine.POST("/reset_enclave", conf, resetEnclave)
+	engine.GET("/profile", read, profilesGet)
+	engine.GET("/profile/:profile_id", read, profileGet)
+	engine.GET("/profile/:profile_id/health", read, profileHealthGet)
+	engine.POST("/profile", control, profilePost)
+	engine.DELETE("/profile", control, profileDel)
+	engine.DELETE("/profile/:profile_id", control, profileDel2)
+	engine.POST("/profile/:profile_id/pause", control, profilePausePost)
+	engine.POST("/profile/:profile_id/resume", control, profileResumePost)
+	engine.GET("/sprofile", read, sprofilesGet)
+	engine.GET("/sprofile/:profile_id", read, sprofileGet)
+	engine.PUT("/sprofile", control, sprofilePut)
+	engine.DELETE("/sprofile", control, sprofileDel)
+	engine.DELETE("/sprofile/:profile_id", control, sprofileDel2)
 	// TODO classic client
-	engine.GET("/sprofile/:profile_id/log", sprofileLogGet)
+	engine.GET("/sprofile/:profile_id/log", read, sprofileLogGet)
 	// TODO classic client
-	engine.DELETE("/sprofile/:profile_id/log", sprofileLogDel)
-	engine.GET("/log/:log_id", logGet)
-	engine.DELETE("/log/:log_id", logDel)
-	engine.PUT("/token", tokenPut)
-	engine.DELETE("/token", tokenDelete)
-	engine.DELETE("/token/:profile_id", tokenDelete2)
-	engine.POST("/tpm/callback", tpmCallbackPost)
+	engine.DELETE("/sprofile/:profile_id/log", control, sprofileLogDel)
+	engine.GET("/log/:log_id", read, logGet)
+	engine.DELETE("/log/:log_id", control, logDel)
+	engine.PUT("/token", admin, tokenPut)
+	engine.DELETE("/token", admin, tokenDelete)
+	engine.DELETE("/token/:profile_id", admin, tokenDelete2)
+	engine.POST("/tpm/callback", admin, tpmCallbackPost)
 	engine.GET("/ping", pingGet)
-	engine.POST("/stop", stopPost)
-	engine.POST("/cleanup", cleanupPost)
-	engine.POST("/restart", restartPost)
-	engine.GET("/status", statusGet)
-	engine.GET("/state", stateGet)
-	engine.POST("/wakeup", wakeupPost)
+	engine.POST("/stop", admin, stopPost)
+	engine.POST("/cleanup", admin, cleanupPost)
+	engine.POST("/restart", admin, restartPost)
+	engine.GET("/status", read, statusGet)
+	engine.GET("/state", read, stateGet)
+	engine.GET("/metrics", read, metricsGet)
+	engine.GET("/history", read, historyGet)
+	engine.POST("/wakeup", control, wakeupPost)
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/history"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+func parseHistoryTime(val string) (tm time.Time, err error) {
+	if val == "" {
+		return
+	}
+
+	unix, e := strconv.ParseInt(val, 10, 64)
+	if e == nil {
+		tm = time.Unix(unix, 0)
+		return
+	}
+
+	tm, err = time.Parse(time.RFC3339, val)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Invalid history time"),
+		}
+		return
+	}
+
+	return
+}
+
+func historyGet(c *gin.Context) {
+	prflId := utils.FilterStr(c.Query("profile_id"))
+
+	since, err := parseHistoryTime(c.Query("since"))
+	if err != nil {
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	until, err := parseHistoryTime(c.Query("until"))
+	if err != nil {
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	records, err := history.Query(prflId, since, until)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, records)
+}
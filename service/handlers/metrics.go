@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-client-electron/service/connection"
+)
+
+var metricsStatuses = []connection.Status{
+	connection.Connecting,
+	connection.Authenticating,
+	connection.Connected,
+	connection.Disconnecting,
+	connection.Disconnected,
+}
+
+var metricsLabelReplacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\n", "\\n",
+)
+
+type metricsWriter struct {
+	buf *bytes.Buffer
+}
+
+func (m *metricsWriter) header(name, typ, help string) {
+	fmt.Fprintf(m.buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(m.buf, "# TYPE %s %s\n", name, typ)
+}
+
+func (m *metricsWriter) value(name string, val interface{},
+	labels ...string) {
+
+	m.buf.WriteString(name)
+
+	if len(labels) > 0 {
+		m.buf.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				m.buf.WriteString(",")
+			}
+			fmt.Fprintf(m.buf, "%s=\"%s\"", labels[i],
+				metricsLabelReplacer.Replace(labels[i+1]))
+		}
+		m.buf.WriteString("}")
+	}
+
+	fmt.Fprintf(m.buf, " %v\n", val)
+}
+
+func metricsGet(c *gin.Context) {
+	conns := connection.GlobalStore.GetAll()
+	metrics := connection.GlobalMetrics.GetAll()
+	now := time.Now().Unix()
+
+	connIds := []string{}
+	for connId := range conns {
+		connIds = append(connIds, connId)
+	}
+	sort.Strings(connIds)
+
+	metricIds := []string{}
+	for metricId := range metrics {
+		metricIds = append(metricIds, metricId)
+	}
+	sort.Strings(metricIds)
+
+	w := &metricsWriter{
+		buf: &bytes.Buffer{},
+	}
+
+	w.header("pritunl_connection_status", "gauge",
+		"Current connection status of the profile")
+	for _, connId := range connIds {
+		conn := conns[connId]
+		for _, status := range metricsStatuses {
+			val := 0
+			if conn.Data.GetStatus() == status {
+				val = 1
+			}
+			w.value("pritunl_connection_status", val,
+				"profile_id", connId,
+				"mode", conn.Profile.Mode,
+				"status", string(status),
+			)
+		}
+	}
+
+	w.header("pritunl_connection_uptime_seconds", "gauge",
+		"Seconds since the profile connection was established")
+	for _, connId := range connIds {
+		conn := conns[connId]
+		uptime := int64(0)
+		if conn.Data.Timestamp != 0 {
+			uptime = now - conn.Data.Timestamp
+		}
+		w.value("pritunl_connection_uptime_seconds", uptime,
+			"profile_id", connId)
+	}
+
+	w.header("pritunl_wg_last_handshake_age_seconds", "gauge",
+		"Seconds since the last WireGuard handshake")
+	for _, connId := range connIds {
+		conn := conns[connId]
+		if conn.Profile.Mode != connection.WgMode {
+			continue
+		}
+
+		lastHandshake := int64(conn.Wg.LastHandshake())
+		if lastHandshake == 0 {
+			continue
+		}
+		w.value("pritunl_wg_last_handshake_age_seconds",
+			now-lastHandshake, "profile_id", connId)
+	}
+
+	w.header("pritunl_keepalive_failures_total", "counter",
+		"Failed keepalive ping requests")
+	for _, metricId := range metricIds {
+		w.value("pritunl_keepalive_failures_total",
+			metrics[metricId].PingFailures, "profile_id", metricId)
+	}
+
+	w.header("pritunl_reconnects_total", "counter",
+		"Automatic reconnects of the profile")
+	for _, metricId := range metricIds {
+		w.value("pritunl_reconnects_total",
+			metrics[metricId].Reconnects, "profile_id", metricId)
+	}
+
+	w.header("pritunl_authorization_attempts_total", "counter",
+		"Authorization requests by remote and result")
+	for _, metricId := range metricIds {
+		authorizations := metrics[metricId].Authorizations
+
+		remotes := []string{}
+		for remote := range authorizations {
+			remotes = append(remotes, remote)
+		}
+		sort.Strings(remotes)
+
+		for _, remote := range remotes {
+			results := authorizations[remote].Results
+
+			resultKeys := []string{}
+			for result := range results {
+				resultKeys = append(resultKeys, result)
+			}
+			sort.Strings(resultKeys)
+
+			for _, result := range resultKeys {
+				w.value("pritunl_authorization_attempts_total",
+					results[result],
+					"profile_id", metricId,
+					"remote", remote,
+					"result", result,
+				)
+			}
+		}
+	}
+
+	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", w.buf.Bytes())
+}
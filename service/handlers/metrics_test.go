@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMetricsWriterValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		metric   string
+		val      interface{}
+		labels   []string
+		expected string
+	}{
+		{
+			name:     "no_labels",
+			metric:   "pritunl_test",
+			val:      1,
+			expected: "pritunl_test 1\n",
+		},
+		{
+			name:     "labels",
+			metric:   "pritunl_test",
+			val:      int64(42),
+			labels:   []string{"profile_id", "abc", "mode", "wg"},
+			expected: "pritunl_test{profile_id=\"abc\",mode=\"wg\"} 42\n",
+		},
+		{
+			name:     "escaped",
+			metric:   "pritunl_test",
+			val:      0,
+			labels:   []string{"remote", "a\"b\\c\nd"},
+			expected: "pritunl_test{remote=\"a\\\"b\\\\c\\nd\"} 0\n",
+		},
+		{
+			name:     "odd_labels",
+			metric:   "pritunl_test",
+			val:      3,
+			labels:   []string{"profile_id", "abc", "mode"},
+			expected: "pritunl_test{profile_id=\"abc\"} 3\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &metricsWriter{
+				buf: &bytes.Buffer{},
+			}
+			w.value(test.metric, test.val, test.labels...)
+
+			if w.buf.String() != test.expected {
+				t.Errorf("value %q, expected %q",
+					w.buf.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestMetricsWriterHeader(t *testing.T) {
+	w := &metricsWriter{
+		buf: &bytes.Buffer{},
+	}
+	w.header("pritunl_test", "gauge", "Test metric")
+
+	expected := "# HELP pritunl_test Test metric\n" +
+		"# TYPE pritunl_test gauge\n"
+	if w.buf.String() != expected {
+		t.Errorf("header %q, expected %q", w.buf.String(), expected)
+	}
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"runtime/debug"
+	"time"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/pritunl/pritunl-client-electron/service/connection"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/health"
+	"github.com/pritunl/pritunl-client-electron/service/schedule"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
 	"github.com/pritunl/pritunl-client-electron/service/types"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
@@ -42,6 +45,11 @@ type profileData struct {
 	ServerBoxPublicKey string                      `json:"server_box_public_key"`
 	TokenTtl           int                         `json:"token_ttl"`
 	Reconnect          bool                        `json:"reconnect"`
+	ReconnectPolicy    *types.ReconnectPolicy      `json:"reconnect_policy"`
+	Proxy              *types.Proxy                `json:"proxy"`
+	TlsPins            []string                    `json:"tls_pins"`
+	KillSwitch         bool                        `json:"kill_switch"`
+	Ipv6Guard          bool                        `json:"ipv6_guard"`
 	Timeout            bool                        `json:"timeout"`
 }
 
@@ -68,6 +76,19 @@ func profileGet(c *gin.Context) {
 	c.JSON(200, prfl)
 }
 
+func profileHealthGet(c *gin.Context) {
+	prflId := utils.FilterStr(c.Param("profile_id"))
+	if prflId == "" {
+		err := &errortypes.ParseError{
+			errors.New("handler: Invalid profile ID"),
+		}
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	c.JSON(200, health.Get(prflId))
+}
+
 func profilePost(c *gin.Context) {
 	data := &profileData{}
 
@@ -89,8 +110,13 @@ func profilePost(c *gin.Context) {
 		return
 	}
 
+	connection.GlobalBackoff.Reset(data.Id)
+	connection.GlobalPause.Clear(data.Id)
+
 	sprfl := sprofile.Get(data.Id)
 	if sprfl != nil {
+		schedule.SetOverride(data.Id, sprfl.Schedule)
+
 		err = sprofile.Activate(data.Id, data.Mode, data.Password)
 		if err != nil {
 			utils.AbortWithError(c, 500, err)
@@ -133,6 +159,11 @@ func profilePost(c *gin.Context) {
 		ServerBoxPublicKey: data.ServerBoxPublicKey,
 		TokenTtl:           data.TokenTtl,
 		Reconnect:          data.Reconnect,
+		ReconnectPolicy:    data.ReconnectPolicy,
+		Proxy:              data.Proxy,
+		TlsPins:            data.TlsPins,
+		KillSwitch:         data.KillSwitch,
+		Ipv6Guard:          data.Ipv6Guard,
 	}
 
 	conn, err = connection.NewConnection(prfl)
@@ -185,9 +216,13 @@ func profileDel(c *gin.Context) {
 	}
 
 	connection.GlobalStore.SetStop(prflId)
+	connection.GlobalBackoff.Reset(prflId)
+	connection.GlobalPause.Clear(prflId)
+	connection.DisableKillSwitch(prflId)
 
 	sprfl := sprofile.Get(prflId)
 	if sprfl != nil {
+		schedule.SetOverride(prflId, sprfl.Schedule)
 		sprofile.Deactivate(prflId)
 		c.JSON(200, nil)
 		return
@@ -212,9 +247,13 @@ func profileDel2(c *gin.Context) {
 	}
 
 	connection.GlobalStore.SetStop(prflId)
+	connection.GlobalBackoff.Reset(prflId)
+	connection.GlobalPause.Clear(prflId)
+	connection.DisableKillSwitch(prflId)
 
 	sprfl := sprofile.Get(prflId)
 	if sprfl != nil {
+		schedule.SetOverride(prflId, sprfl.Schedule)
 		sprofile.Deactivate(prflId)
 		c.JSON(200, nil)
 		return
@@ -227,3 +266,75 @@ func profileDel2(c *gin.Context) {
 
 	c.JSON(200, nil)
 }
+
+type profilePauseData struct {
+	Duration int `json:"duration"`
+}
+
+func profilePausePost(c *gin.Context) {
+	prflId := utils.FilterStr(c.Param("profile_id"))
+	if prflId == "" {
+		err := &errortypes.ParseError{
+			errors.New("handler: Invalid profile ID"),
+		}
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	data := &profilePauseData{}
+	if c.Request.ContentLength != 0 {
+		err := c.Bind(data)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "handler: Bind error"),
+			}
+			utils.AbortWithError(c, 400, err)
+			return
+		}
+	}
+
+	if data.Duration < 0 ||
+		data.Duration > int(connection.PauseMaxDuration/time.Second) {
+
+		err := &errortypes.ParseError{
+			errors.New("handler: Invalid pause duration"),
+		}
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	conn := connection.GlobalStore.Get(prflId)
+	if conn == nil {
+		err := &errortypes.NotFoundError{
+			errors.New("handler: Profile not connected"),
+		}
+		utils.AbortWithError(c, 404, err)
+		return
+	}
+
+	connection.GlobalPause.Pause(conn,
+		time.Duration(data.Duration)*time.Second)
+
+	c.JSON(200, nil)
+}
+
+func profileResumePost(c *gin.Context) {
+	prflId := utils.FilterStr(c.Param("profile_id"))
+	if prflId == "" {
+		err := &errortypes.ParseError{
+			errors.New("handler: Invalid profile ID"),
+		}
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	if !connection.GlobalPause.Resume(prflId) {
+		err := &errortypes.NotFoundError{
+			errors.New("handler: Profile not paused"),
+		}
+		utils.AbortWithError(c, 404, err)
+		return
+	}
+
+	c.JSON(200, nil)
+}
@@ -5,7 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/pritunl/pritunl-client-electron/service/connection"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/schedule"
 	"github.com/pritunl/pritunl-client-electron/service/sprofile"
+	"github.com/pritunl/pritunl-client-electron/service/trusted"
 	"github.com/pritunl/pritunl-client-electron/service/types"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 )
@@ -47,6 +49,13 @@ type sprofileData struct {
 	ServerBoxPublicKey string                      `json:"server_box_public_key"`
 	RegistrationKey    string                      `json:"registration_key"`
 	OvpnData           string                      `json:"ovpn_data"`
+	ReconnectPolicy    *types.ReconnectPolicy      `json:"reconnect_policy"`
+	Proxy              *types.Proxy                `json:"proxy"`
+	TlsPins            []string                    `json:"tls_pins"`
+	Schedule           *types.Schedule             `json:"schedule"`
+	TrustedNetworks    []types.TrustedNetwork      `json:"trusted_networks"`
+	KillSwitch         bool                        `json:"kill_switch"`
+	Ipv6Guard          bool                        `json:"ipv6_guard"`
 }
 
 func sprofilesGet(c *gin.Context) {
@@ -105,6 +114,18 @@ func sprofilePut(c *gin.Context) {
 		return
 	}
 
+	err = schedule.Validate(data.Schedule)
+	if err != nil {
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
+	err = trusted.Validate(data.TrustedNetworks)
+	if err != nil {
+		utils.AbortWithError(c, 400, err)
+		return
+	}
+
 	prfl := &sprofile.Sprofile{
 		Id:                 data.Id,
 		Name:               data.Name,
@@ -141,6 +162,25 @@ func sprofilePut(c *gin.Context) {
 		ServerBoxPublicKey: data.ServerBoxPublicKey,
 		RegistrationKey:    data.RegistrationKey,
 		OvpnData:           data.OvpnData,
+		ReconnectPolicy:    data.ReconnectPolicy,
+		Proxy:              data.Proxy,
+		TlsPins:            data.TlsPins,
+		Schedule:           data.Schedule,
+		TrustedNetworks:    data.TrustedNetworks,
+		KillSwitch:         data.KillSwitch,
+		Ipv6Guard:          data.Ipv6Guard,
+	}
+
+	curPrfl := sprofile.Get(prfl.Id)
+	if curPrfl != nil {
+		prfl.TlsPinsTofu = curPrfl.Copy().TlsPinsTofu
+		prfl.Proxy.Unredact(curPrfl.Proxy)
+	}
+
+	schedule.ClearOverride(prfl.Id)
+	trusted.Clear(prfl.Id)
+	if !prfl.KillSwitch {
+		connection.DisableKillSwitch(prfl.Id)
 	}
 
 	err = prfl.Commit()
@@ -181,6 +221,10 @@ func sprofileDel(c *gin.Context) {
 	}
 
 	sprofile.Remove(prflId)
+	connection.GlobalMetrics.Remove(prflId)
+	schedule.ClearOverride(prflId)
+	trusted.Clear(prflId)
+	connection.DisableKillSwitch(prflId)
 
 	c.JSON(200, nil)
 }
@@ -203,6 +247,10 @@ func sprofileDel2(c *gin.Context) {
 	}
 
 	sprofile.Remove(prflId)
+	connection.GlobalMetrics.Remove(prflId)
+	schedule.ClearOverride(prflId)
+	trusted.Clear(prflId)
+	connection.DisableKillSwitch(prflId)
 
 	c.JSON(200, nil)
 }
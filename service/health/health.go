@@ -0,0 +1,372 @@
+package health
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	Success = "success"
+	Denied  = "denied"
+	Offline = "offline"
+	Error   = "error"
+
+	NeutralScore = 50
+
+	weight        = 0.3
+	failureWindow = 10 * time.Minute
+	expireTtl     = 14 * 24 * time.Hour
+	saveDelay     = 5 * time.Second
+)
+
+var (
+	profiles  = map[string]map[string]*Remote{}
+	loaded    = false
+	saveTimer *time.Timer
+	lock      = sync.Mutex{}
+)
+
+// Remote stores the recent outcomes of a remote, the rates and times are
+// exponentially weighted so older outcomes have less effect on the score
+type Remote struct {
+	Host             string    `json:"host"`
+	Score            float64   `json:"score"`
+	SuccessRate      float64   `json:"success_rate"`
+	HandshakeRate    float64   `json:"handshake_rate"`
+	AuthorizeTime    float64   `json:"authorize_time"`
+	ConnectTime      float64   `json:"connect_time"`
+	Attempts         int       `json:"attempts"`
+	Failures         int       `json:"failures"`
+	Offline          int       `json:"offline"`
+	Handshakes       int       `json:"handshakes"`
+	LastResult       string    `json:"last_result"`
+	LastSuccess      time.Time `json:"last_success"`
+	LastFailure      time.Time `json:"last_failure"`
+	LastHandshake    time.Time `json:"last_handshake"`
+	LastHandshakeErr time.Time `json:"last_handshake_error"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+func ewma(cur, val float64, count int) float64 {
+	if count <= 1 {
+		return val
+	}
+	return cur*(1-weight) + val*weight
+}
+
+// calculate updates the score, successful fast remotes score higher and
+// remotes that failed recently are pushed back
+func (r *Remote) calculate() {
+	if r.Attempts == 0 && r.Handshakes == 0 {
+		r.Score = NeutralScore
+		return
+	}
+
+	score := 0.0
+	if r.Attempts > 0 {
+		score += r.SuccessRate * 60
+	} else {
+		score += 30
+	}
+	if r.Handshakes > 0 {
+		score += r.HandshakeRate * 30
+	} else {
+		score += 15
+	}
+
+	latency := r.AuthorizeTime + r.ConnectTime/4
+	score += 10 * (1 - math.Min(latency, 10)/10)
+
+	if !r.LastFailure.IsZero() && r.LastFailure.After(r.LastSuccess) &&
+		time.Since(r.LastFailure) < failureWindow {
+
+		score -= 40
+	}
+	if !r.LastHandshakeErr.IsZero() &&
+		r.LastHandshakeErr.After(r.LastHandshake) &&
+		time.Since(r.LastHandshakeErr) < failureWindow {
+
+		score -= 30
+	}
+	if r.LastResult == Offline {
+		score -= 20
+	}
+
+	r.Score = math.Round(score*100) / 100
+}
+
+func GetPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-health.json")
+}
+
+func load() {
+	if loaded {
+		return
+	}
+	loaded = true
+
+	data, err := ioutil.ReadFile(GetPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "health: Failed to read health"),
+			}
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("health: Failed to load remote health")
+		}
+		return
+	}
+
+	loadedProfiles := map[string]map[string]*Remote{}
+	err = json.Unmarshal(data, &loadedProfiles)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "health: Failed to parse health"),
+		}
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("health: Failed to load remote health")
+		return
+	}
+
+	for prflId, remotes := range loadedProfiles {
+		for host, remote := range remotes {
+			if remote == nil || time.Since(remote.Timestamp) > expireTtl {
+				delete(remotes, host)
+			}
+		}
+		if len(remotes) > 0 {
+			profiles[prflId] = remotes
+		}
+	}
+}
+
+func save() (err error) {
+	pth := GetPath()
+	tmpPth := pth + ".tmp"
+
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "health: Failed to marshal health"),
+		}
+		return
+	}
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(tmpPth, data, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "health: Failed to write health"),
+		}
+		return
+	}
+
+	err = os.Rename(tmpPth, pth)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "health: Failed to move health"),
+		}
+		return
+	}
+
+	return
+}
+
+func update(prflId, host string, handler func(remote *Remote)) {
+	if prflId == "" || host == "" {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	remotes := profiles[prflId]
+	if remotes == nil {
+		remotes = map[string]*Remote{}
+		profiles[prflId] = remotes
+	}
+
+	remote := remotes[host]
+	if remote == nil {
+		remote = &Remote{
+			Host: host,
+		}
+		remotes[host] = remote
+	}
+
+	handler(remote)
+	remote.Timestamp = time.Now()
+	remote.calculate()
+
+	scheduleSave()
+}
+
+// scheduleSave batches the updates within the save delay into a single
+// write, must be called with the lock held
+func scheduleSave() {
+	if saveTimer != nil {
+		return
+	}
+
+	saveTimer = time.AfterFunc(saveDelay, func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if saveTimer == nil {
+			return
+		}
+		saveTimer = nil
+
+		err := save()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("health: Failed to save remote health")
+		}
+	})
+}
+
+// Flush writes any pending updates, called before the service exits
+func Flush() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if saveTimer == nil {
+		return
+	}
+	saveTimer.Stop()
+	saveTimer = nil
+
+	err := save()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("health: Failed to save remote health")
+	}
+}
+
+// Authorize records the outcome of an authorize request, a denied request
+// is counted as a healthy remote
+func Authorize(prflId, host, result string, duration time.Duration) {
+	update(prflId, host, func(remote *Remote) {
+		remote.Attempts += 1
+		remote.LastResult = result
+
+		success := 0.0
+		switch result {
+		case Success, Denied:
+			success = 1
+			remote.LastSuccess = time.Now()
+			remote.AuthorizeTime = ewma(remote.AuthorizeTime,
+				duration.Seconds(), remote.Attempts-remote.Failures)
+		case Offline:
+			remote.Offline += 1
+			remote.Failures += 1
+			remote.LastFailure = time.Now()
+		default:
+			remote.Failures += 1
+			remote.LastFailure = time.Now()
+		}
+
+		remote.SuccessRate = ewma(remote.SuccessRate, success,
+			remote.Attempts)
+	})
+}
+
+// Handshake records the outcome of the tunnel handshake with the time
+// from the start of the connection
+func Handshake(prflId, host string, success bool, duration time.Duration) {
+	update(prflId, host, func(remote *Remote) {
+		remote.Handshakes += 1
+
+		value := 0.0
+		if success {
+			value = 1
+			remote.LastHandshake = time.Now()
+			remote.ConnectTime = ewma(remote.ConnectTime,
+				duration.Seconds(), remote.Handshakes)
+		} else {
+			remote.LastHandshakeErr = time.Now()
+		}
+
+		remote.HandshakeRate = ewma(remote.HandshakeRate, value,
+			remote.Handshakes)
+	})
+}
+
+func Get(prflId string) (remotes map[string]*Remote) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	remotes = map[string]*Remote{}
+	for host, remote := range profiles[prflId] {
+		remoteCopy := *remote
+		remoteCopy.calculate()
+		remotes[host] = &remoteCopy
+	}
+
+	return
+}
+
+// Scores returns the score of each known remote, unknown remotes should
+// use NeutralScore
+func Scores(prflId string) (scores map[string]float64) {
+	scores = map[string]float64{}
+
+	for host, remote := range Get(prflId) {
+		scores[host] = remote.Score
+	}
+
+	return
+}
+
+// IsFailing returns true if the remote failed more recently than it
+// succeeded within the failure window
+func IsFailing(prflId, host string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	remote := profiles[prflId][host]
+	if remote == nil {
+		return false
+	}
+
+	if !remote.LastFailure.IsZero() &&
+		remote.LastFailure.After(remote.LastSuccess) &&
+		time.Since(remote.LastFailure) < failureWindow {
+
+		return true
+	}
+	if !remote.LastHandshakeErr.IsZero() &&
+		remote.LastHandshakeErr.After(remote.LastHandshake) &&
+		time.Since(remote.LastHandshakeErr) < failureWindow {
+
+		return true
+	}
+
+	return false
+}
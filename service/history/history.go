@@ -0,0 +1,259 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusRecord = "status"
+	EventRecord  = "event"
+
+	maxSize   = 4 * 1024 * 1024
+	queueSize = 1024
+)
+
+var (
+	lock    = sync.Mutex{}
+	queue   = make(chan *Record, queueSize)
+	pending = sync.WaitGroup{}
+)
+
+// Duration is the connected time for disconnected records, all other
+// records use the time since the connection attempt started.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	ProfileId string    `json:"profile_id"`
+	Mode      string    `json:"mode"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Remote    string    `json:"remote"`
+	Reason    string    `json:"reason"`
+	Duration  int64     `json:"duration"`
+}
+
+func GetPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-history.log")
+}
+
+func rotate(pth string) (err error) {
+	stat, err := os.Stat(pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "history: Failed to stat history"),
+			}
+		}
+		return
+	}
+
+	if stat.Size() < maxSize {
+		return
+	}
+
+	err = os.Rename(pth, pth+".1")
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "history: Failed to rotate history"),
+		}
+		return
+	}
+
+	return
+}
+
+func Append(record *Record) (err error) {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "history: Failed to marshal record"),
+		}
+		return
+	}
+
+	pth := GetPath()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	err = rotate(pth)
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(pth, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "history: Failed to open history"),
+		}
+		return
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "history: Failed to write history"),
+		}
+		return
+	}
+
+	return
+}
+
+// Add queues the history entry for the writer, records are written in
+// the order they are added without blocking the caller
+func Add(record *Record) {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	pending.Add(1)
+	select {
+	case queue <- record:
+	default:
+		pending.Done()
+		logrus.WithFields(logrus.Fields{
+			"profile_id": record.ProfileId,
+			"state":      record.State,
+		}).Error("history: History queue full, dropping record")
+	}
+}
+
+// Flush waits for the queued records to be written
+func Flush() {
+	pending.Wait()
+}
+
+func write(record *Record) {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"profile_id": record.ProfileId,
+				"trace":      string(debug.Stack()),
+				"panic":      panc,
+			}).Error("history: Writer panic")
+		}
+	}()
+	defer pending.Done()
+
+	err := Append(record)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": record.ProfileId,
+			"state":      record.State,
+			"error":      err,
+		}).Error("history: Failed to write history record")
+	}
+}
+
+func writer() {
+	for record := range queue {
+		write(record)
+	}
+}
+
+func init() {
+	go writer()
+}
+
+func readFile(pth, prflId string, since, until time.Time,
+	records []*Record) (newRecords []*Record, err error) {
+
+	newRecords = records
+
+	file, err := os.Open(pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "history: Failed to open history"),
+			}
+		}
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := &Record{}
+		e := json.Unmarshal(scanner.Bytes(), record)
+		if e != nil {
+			continue
+		}
+
+		if prflId != "" && record.ProfileId != prflId {
+			continue
+		}
+		if !since.IsZero() && record.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && record.Timestamp.After(until) {
+			continue
+		}
+
+		newRecords = append(newRecords, record)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "history: Failed to read history"),
+		}
+		return
+	}
+
+	return
+}
+
+func Query(prflId string, since, until time.Time) (
+	records []*Record, err error) {
+
+	pth := GetPath()
+	records = []*Record{}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	records, err = readFile(pth+".1", prflId, since, until, records)
+	if err != nil {
+		return
+	}
+
+	records, err = readFile(pth, prflId, since, until, records)
+	if err != nil {
+		return
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	return
+}
@@ -0,0 +1,323 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/command"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/log"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	Connecting   = "connecting"
+	Connected    = "connected"
+	Disconnected = "disconnected"
+	AuthFailure  = "auth-failure"
+
+	defaultTimeout = 30 * time.Second
+	waitDelay      = 5 * time.Second
+)
+
+var (
+	queues     = map[string]*queue{}
+	queuesLock = sync.Mutex{}
+)
+
+type job struct {
+	hook string
+	data *Data
+}
+
+// queue runs the hooks of a profile one at a time in the order they
+// were triggered, the worker exits when the queue is empty
+type queue struct {
+	jobs    []*job
+	running bool
+}
+
+type Data struct {
+	ProfileId  string
+	Mode       string
+	Iface      string
+	ClientAddr string
+	ServerAddr string
+	DnsServers []string
+	Routes     []string
+	Routes6    []string
+}
+
+func (d *Data) env(hook string) []string {
+	return append(os.Environ(),
+		"PRITUNL_HOOK="+hook,
+		"PRITUNL_PROFILE_ID="+d.ProfileId,
+		"PRITUNL_MODE="+d.Mode,
+		"PRITUNL_IFACE="+d.Iface,
+		"PRITUNL_CLIENT_ADDR="+d.ClientAddr,
+		"PRITUNL_SERVER_ADDR="+d.ServerAddr,
+		"PRITUNL_DNS_SERVERS="+strings.Join(d.DnsServers, " "),
+		"PRITUNL_ROUTES="+strings.Join(d.Routes, " "),
+		"PRITUNL_ROUTES6="+strings.Join(d.Routes6, " "),
+	)
+}
+
+func GetPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(utils.GetWinDrive(), "ProgramData",
+			"Pritunl", "Hooks")
+	case "darwin":
+		return filepath.Join("/", "var",
+			"lib", "pritunl-client", "hooks")
+	case "linux":
+		return filepath.Join("/", "var",
+			"lib", "pritunl-client", "hooks")
+	default:
+		panic("hooks: Not implemented")
+	}
+}
+
+func getTimeout() time.Duration {
+	if config.Config.HooksTimeout > 0 {
+		return time.Duration(config.Config.HooksTimeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// getScripts returns the executables for the hook, only files and
+// directories that are owned by root and not writable by other users
+// are permitted
+func getScripts(hook string) (scripts []string, err error) {
+	hooksPath := GetPath()
+	hookPath := filepath.Join(hooksPath, hook)
+
+	for _, pth := range []string{hooksPath, hookPath} {
+		stat, e := os.Stat(pth)
+		if e != nil {
+			if os.IsNotExist(e) {
+				return
+			}
+
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "hooks: Failed to stat hooks directory"),
+			}
+			return
+		}
+
+		if !stat.IsDir() {
+			return
+		}
+
+		if !isSecure(pth, stat) {
+			err = &errortypes.ReadError{
+				errors.Newf("hooks: Insecure hooks directory '%s'", pth),
+			}
+			return
+		}
+	}
+
+	files, err := ioutil.ReadDir(hookPath)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "hooks: Failed to read hooks directory"),
+		}
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	for _, file := range files {
+		pth := filepath.Join(hookPath, file.Name())
+
+		if strings.HasPrefix(file.Name(), ".") ||
+			!file.Mode().IsRegular() || !isExecutable(file) {
+
+			continue
+		}
+
+		if !isSecure(pth, file) {
+			logrus.WithFields(logrus.Fields{
+				"hook": hook,
+				"path": pth,
+			}).Warn("hooks: Skipping insecure hook script")
+			continue
+		}
+
+		scripts = append(scripts, pth)
+	}
+
+	return
+}
+
+func runScript(pth, hook string, data *Data) (output string, err error) {
+	buf := &bytes.Buffer{}
+
+	cmd := command.Command(pth)
+	cmd.Env = data.env(hook)
+	cmd.Dir = filepath.Dir(pth)
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrapf(err, "hooks: Failed to exec '%s'", pth),
+		}
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	timeout := getTimeout()
+	select {
+	case err = <-done:
+		output = buf.String()
+		if err != nil {
+			err = &errortypes.ExecError{
+				errors.Wrapf(err, "hooks: Hook '%s' failed", pth),
+			}
+			return
+		}
+	case <-time.After(timeout):
+		killProcessGroup(cmd)
+		<-done
+		output = buf.String()
+		err = &errortypes.ExecError{
+			errors.Newf("hooks: Hook '%s' timed out after %s",
+				pth, timeout),
+		}
+		return
+	}
+
+	return
+}
+
+func pushLog(prflId, hook, pth, output string, err error) {
+	lines := []string{
+		fmt.Sprintf("%s hook: Running %s hook '%s'",
+			time.Now().Format("2006-01-02 15:04:05"), hook, pth),
+	}
+
+	output = strings.TrimSpace(output)
+	if output != "" {
+		lines = append(lines, output)
+	}
+	if err != nil {
+		lines = append(lines, err.Error())
+	}
+
+	e := log.ProfilePushLog(prflId, strings.Join(lines, "\n"))
+	if e != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+			"hook":       hook,
+			"error":      e,
+		}).Error("hooks: Failed to push profile log output")
+	}
+}
+
+func run(hook string, data *Data) {
+	scripts, err := getScripts(hook)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": data.ProfileId,
+			"hook":       hook,
+			"error":      err,
+		}).Error("hooks: Failed to load hook scripts")
+		return
+	}
+
+	if len(scripts) == 0 {
+		return
+	}
+
+	for _, pth := range scripts {
+		output, err := runScript(pth, hook, data)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"profile_id": data.ProfileId,
+				"hook":       hook,
+				"path":       pth,
+				"error":      err,
+			}).Error("hooks: Hook script failed")
+		}
+
+		pushLog(data.ProfileId, hook, pth, output, err)
+	}
+}
+
+func runJob(jb *job) {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"profile_id": jb.data.ProfileId,
+				"hook":       jb.hook,
+				"trace":      string(debug.Stack()),
+				"panic":      panc,
+			}).Error("hooks: Hook panic")
+		}
+	}()
+
+	run(jb.hook, jb.data)
+}
+
+func runQueue(prflId string, que *queue) {
+	for {
+		queuesLock.Lock()
+		if len(que.jobs) == 0 {
+			que.running = false
+			delete(queues, prflId)
+			queuesLock.Unlock()
+			return
+		}
+		jb := que.jobs[0]
+		que.jobs = que.jobs[1:]
+		queuesLock.Unlock()
+
+		runJob(jb)
+	}
+}
+
+// Run executes the hook scripts in the background, scripts for a profile
+// are run one at a time in the order the hooks were triggered
+func Run(hook string, data *Data) {
+	queuesLock.Lock()
+	defer queuesLock.Unlock()
+
+	que := queues[data.ProfileId]
+	if que == nil {
+		que = &queue{}
+		queues[data.ProfileId] = que
+	}
+
+	que.jobs = append(que.jobs, &job{
+		hook: hook,
+		data: data,
+	})
+
+	if !que.running {
+		que.running = true
+		go runQueue(data.ProfileId, que)
+	}
+}
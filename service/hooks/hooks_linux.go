@@ -0,0 +1,38 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode().Perm()&0111 != 0
+}
+
+func isSecure(pth string, info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Uid != 0 {
+		return false
+	}
+
+	return info.Mode().Perm()&0022 == 0
+}
+
+// setProcessGroup starts the hook in a new process group so children left
+// running by the script can be killed with it
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	_ = cmd.Process.Kill()
+}
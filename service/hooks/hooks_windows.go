@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/pritunl/pritunl-client-electron/service/command"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	trustedInstallerSid = "S-1-5-80-956008885-3418522649-1831038044-" +
+		"1853292631-2271478464"
+
+	fileWriteData       = 0x0002
+	fileAppendData      = 0x0004
+	fileWriteEa         = 0x0010
+	fileDeleteChild     = 0x0040
+	fileWriteAttributes = 0x0100
+
+	writeMask = fileWriteData | fileAppendData | fileWriteEa |
+		fileDeleteChild | fileWriteAttributes | windows.DELETE |
+		windows.WRITE_DAC | windows.WRITE_OWNER | windows.GENERIC_WRITE |
+		windows.GENERIC_ALL
+)
+
+func isExecutable(info os.FileInfo) bool {
+	switch strings.ToLower(filepath.Ext(info.Name())) {
+	case ".exe", ".bat", ".cmd":
+		return true
+	}
+
+	return false
+}
+
+func isTrustedSid(sid *windows.SID) bool {
+	if sid == nil {
+		return false
+	}
+
+	return sid.IsWellKnown(windows.WinLocalSystemSid) ||
+		sid.IsWellKnown(windows.WinBuiltinAdministratorsSid) ||
+		sid.String() == trustedInstallerSid
+}
+
+// isSecure requires the owner to be SYSTEM or Administrators and write
+// access to be granted only to SYSTEM and Administrators
+func isSecure(pth string, info os.FileInfo) bool {
+	sd, err := windows.GetNamedSecurityInfo(
+		pth,
+		windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|
+			windows.DACL_SECURITY_INFORMATION,
+	)
+	if err != nil {
+		return false
+	}
+
+	owner, _, err := sd.Owner()
+	if err != nil || !isTrustedSid(owner) {
+		return false
+	}
+
+	dacl, _, err := sd.DACL()
+	if err != nil || dacl == nil {
+		return false
+	}
+
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		err = windows.GetAce(dacl, i, &ace)
+		if err != nil {
+			return false
+		}
+
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE ||
+			ace.Header.AceFlags&windows.INHERIT_ONLY_ACE != 0 ||
+			uint32(ace.Mask)&writeMask == 0 {
+
+			continue
+		}
+
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if !isTrustedSid(sid) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setProcessGroup starts the hook in a new process group so children left
+// running by the script can be killed with it
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	_ = command.Command("taskkill", "/F", "/T", "/PID",
+		strconv.Itoa(cmd.Process.Pid)).Run()
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,157 @@
+package ipv6guard
+
+import (
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	guards = map[string]*Guard{}
+	lock   = sync.Mutex{}
+)
+
+// Guard stores the remote addresses and the tunnel interface allowed while
+// global IPv6 traffic of the profile is blocked
+type Guard struct {
+	Id    string
+	Addrs []string
+	Iface string
+}
+
+type Rules struct {
+	Addrs6 []string
+	Ifaces []string
+}
+
+// getRules merges the allowed addresses and interfaces of all guards
+func getRules() (rules *Rules) {
+	rules = &Rules{
+		Addrs6: []string{},
+		Ifaces: append([]string{}, tunnelIfaces...),
+	}
+
+	addrs := map[string]bool{}
+	ifaces := map[string]bool{}
+	for _, iface := range rules.Ifaces {
+		ifaces[iface] = true
+	}
+
+	for _, guard := range guards {
+		for _, addr := range guard.Addrs {
+			ip := net.ParseIP(addr)
+			if ip == nil || ip.To4() != nil || addrs[ip.String()] {
+				continue
+			}
+			addrs[ip.String()] = true
+			rules.Addrs6 = append(rules.Addrs6, ip.String())
+		}
+
+		if guard.Iface != "" && !ifaces[guard.Iface] {
+			ifaces[guard.Iface] = true
+			rules.Ifaces = append(rules.Ifaces, guard.Iface)
+		}
+	}
+
+	sort.Strings(rules.Addrs6)
+
+	return
+}
+
+func update() (err error) {
+	if len(guards) == 0 {
+		err = removeRules()
+		if err != nil {
+			return
+		}
+		return
+	}
+
+	err = applyRules(getRules())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Enable blocks global IPv6 traffic outside of the tunnel interfaces
+// except to the remote addresses of the profile
+func Enable(prflId string, addrs []string, iface string) (err error) {
+	if !Supported {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if guards[prflId] == nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+		}).Info("ipv6guard: Enabling IPv6 leak guard")
+	}
+
+	guards[prflId] = &Guard{
+		Id:    prflId,
+		Addrs: addrs,
+		Iface: iface,
+	}
+
+	err = update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Disable removes the guard of the profile, the rules are removed when no
+// other profile has an active guard
+func Disable(prflId string) (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if guards[prflId] == nil {
+		return
+	}
+	delete(guards, prflId)
+
+	logrus.WithFields(logrus.Fields{
+		"profile_id": prflId,
+	}).Info("ipv6guard: Disabling IPv6 leak guard")
+
+	err = update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func IsActive(prflId string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return guards[prflId] != nil
+}
+
+// Reset removes the rules left by a previous run of the service
+func Reset() (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	guards = map[string]*Guard{}
+
+	if !Supported {
+		return
+	}
+
+	err = removeRules()
+	if err != nil {
+		return
+	}
+
+	return
+}
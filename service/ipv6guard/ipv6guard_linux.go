@@ -0,0 +1,78 @@
+package ipv6guard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	Supported = true
+	tableName = "pritunl_ipv6guard"
+)
+
+var tunnelIfaces = []string{
+	"tun*",
+	"wg*",
+}
+
+func formatSet(vals []string) string {
+	return "{ " + strings.Join(vals, ", ") + " }"
+}
+
+// getScript rejects IPv6 traffic to global unicast addresses, link-local,
+// unique local and multicast traffic is still allowed for the local network
+func getScript(rules *Rules) string {
+	script := fmt.Sprintf("table inet %s\n", tableName)
+	script += fmt.Sprintf("delete table inet %s\n", tableName)
+	script += fmt.Sprintf("table inet %s {\n", tableName)
+
+	script += "\tchain output {\n"
+	script += "\t\ttype filter hook output priority 0; policy accept;\n"
+	script += "\t\tmeta nfproto != ipv6 accept\n"
+	script += "\t\toifname \"lo\" accept\n"
+	for _, iface := range rules.Ifaces {
+		script += fmt.Sprintf("\t\toifname \"%s\" accept\n", iface)
+	}
+	if len(rules.Addrs6) > 0 {
+		script += fmt.Sprintf("\t\tip6 daddr %s accept\n",
+			formatSet(rules.Addrs6))
+	}
+	script += "\t\tip6 daddr != 2000::/3 accept\n"
+	script += "\t\treject with icmpx type admin-prohibited\n"
+	script += "\t}\n"
+
+	script += "}\n"
+
+	return script
+}
+
+func applyRules(rules *Rules) (err error) {
+	_, err = utils.ExecInputOutput(getScript(rules), "nft", "-f", "-")
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "ipv6guard: Failed to apply nftables rules"),
+		}
+		return
+	}
+
+	return
+}
+
+func removeRules() (err error) {
+	script := fmt.Sprintf("table inet %s\ndelete table inet %s\n",
+		tableName, tableName)
+
+	_, err = utils.ExecInputOutput(script, "nft", "-f", "-")
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "ipv6guard: Failed to remove nftables rules"),
+		}
+		return
+	}
+
+	return
+}
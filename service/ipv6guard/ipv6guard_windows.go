@@ -0,0 +1,13 @@
+package ipv6guard
+
+const Supported = false
+
+var tunnelIfaces = []string{}
+
+func applyRules(rules *Rules) (err error) {
+	return
+}
+
+func removeRules() (err error) {
+	return
+}
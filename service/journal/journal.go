@@ -0,0 +1,356 @@
+package journal
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FileEntry      = "file"
+	WgIfaceEntry   = "wg_iface"
+	RouteEntry     = "route"
+	DnsEntry       = "dns"
+	ProcessEntry   = "process"
+	Ipv6GuardEntry = "ipv6_guard"
+)
+
+var (
+	entries = []*Entry{}
+	lock    = sync.Mutex{}
+)
+
+// Entry records a network side effect applied by a connection. Value
+// holds the file path, interface, route network, process id or IPv6
+// guard profile id and Path holds the related config file, interface,
+// executable or DNS backend.
+type Entry struct {
+	ConnId    string    `json:"conn_id"`
+	ProfileId string    `json:"profile_id"`
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (e *Entry) Fields() logrus.Fields {
+	return logrus.Fields{
+		"conn_id":    e.ConnId,
+		"profile_id": e.ProfileId,
+		"type":       e.Type,
+		"value":      e.Value,
+		"path":       e.Path,
+	}
+}
+
+func GetPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-journal.json")
+}
+
+func save() (err error) {
+	pth := GetPath()
+	tmpPth := pth + ".tmp"
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "journal: Failed to marshal journal"),
+		}
+		return
+	}
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(tmpPth,
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "journal: Failed to open journal"),
+		}
+		return
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "journal: Failed to write journal"),
+		}
+		return
+	}
+
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "journal: Failed to sync journal"),
+		}
+		return
+	}
+
+	err = file.Close()
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "journal: Failed to close journal"),
+		}
+		return
+	}
+
+	err = os.Rename(tmpPth, pth)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "journal: Failed to move journal"),
+		}
+		return
+	}
+
+	return
+}
+
+func load() (loaded []*Entry, err error) {
+	loaded = []*Entry{}
+
+	data, err := ioutil.ReadFile(GetPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "journal: Failed to read journal"),
+			}
+		}
+		return
+	}
+
+	err = json.Unmarshal(data, &loaded)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "journal: Failed to parse journal"),
+		}
+		return
+	}
+
+	return
+}
+
+// Add writes the entry to disk, must be called before the side effect
+// is applied
+func Add(connId, prflId, typ, value, pth string) {
+	AddAll(connId, prflId, []*Entry{
+		{
+			Type:  typ,
+			Value: value,
+			Path:  pth,
+		},
+	})
+}
+
+// AddAll writes the entries of a connection step to disk with a single
+// save, must be called before the side effects are applied
+func AddAll(connId, prflId string, ents []*Entry) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := time.Now()
+	added := false
+
+	for _, entry := range ents {
+		exists := false
+		for _, ent := range entries {
+			if ent.ConnId == connId && ent.Type == entry.Type &&
+				ent.Value == entry.Value {
+
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+
+		entries = append(entries, &Entry{
+			ConnId:    connId,
+			ProfileId: prflId,
+			Type:      entry.Type,
+			Value:     entry.Value,
+			Path:      entry.Path,
+			Timestamp: now,
+		})
+		added = true
+	}
+
+	if !added {
+		return
+	}
+
+	err := save()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"conn_id":    connId,
+			"profile_id": prflId,
+			"entries":    len(ents),
+			"error":      err,
+		}).Error("journal: Failed to save journal entries")
+	}
+}
+
+// Remove drops a single entry after the side effect has been reverted
+func Remove(connId, typ, value string) {
+	RemoveAll(connId, []*Entry{
+		{
+			Type:  typ,
+			Value: value,
+		},
+	})
+}
+
+// RemoveAll drops the entries matching the type and value of the given
+// entries with a single save after the side effects have been reverted
+func RemoveAll(connId string, ents []*Entry) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	newEntries := []*Entry{}
+	for _, ent := range entries {
+		if ent.ConnId == connId && matchEntry(ents, ent) {
+			continue
+		}
+		newEntries = append(newEntries, ent)
+	}
+
+	if len(newEntries) == len(entries) {
+		return
+	}
+	entries = newEntries
+
+	err := save()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"conn_id": connId,
+			"entries": len(ents),
+			"error":   err,
+		}).Error("journal: Failed to save journal")
+	}
+}
+
+func matchEntry(ents []*Entry, entry *Entry) bool {
+	for _, ent := range ents {
+		if ent.Type == entry.Type && ent.Value == entry.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Clear drops all entries of a connection once it has been torn down
+func Clear(connId string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	newEntries := []*Entry{}
+	for _, ent := range entries {
+		if ent.ConnId == connId {
+			continue
+		}
+		newEntries = append(newEntries, ent)
+	}
+
+	if len(newEntries) == len(entries) {
+		return
+	}
+	entries = newEntries
+
+	err := save()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"conn_id": connId,
+			"error":   err,
+		}).Error("journal: Failed to save journal")
+	}
+}
+
+// Replay tears down the side effects left by a previous run of the
+// service, must be called on startup before any connections are started
+func Replay() (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	loaded, err := load()
+	if err != nil {
+		return
+	}
+
+	if len(loaded) == 0 {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"entries": len(loaded),
+	}).Warn("journal: Replaying journal from previous run")
+
+	typeOrder := []string{
+		ProcessEntry,
+		WgIfaceEntry,
+		RouteEntry,
+		DnsEntry,
+		Ipv6GuardEntry,
+		FileEntry,
+	}
+
+	for _, typ := range typeOrder {
+		for i := len(loaded) - 1; i >= 0; i-- {
+			entry := loaded[i]
+			if entry.Type != typ {
+				continue
+			}
+
+			e := teardown(entry)
+			if e != nil {
+				logrus.WithFields(entry.Fields()).WithFields(logrus.Fields{
+					"error": e,
+				}).Error("journal: Failed to teardown journal entry")
+			} else {
+				logrus.WithFields(entry.Fields()).Info(
+					"journal: Removed orphaned entry")
+			}
+		}
+	}
+
+	entries = []*Entry{}
+
+	err = save()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func removeFile(entry *Entry) (err error) {
+	err = os.Remove(entry.Value)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "journal: Failed to remove file"),
+			}
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,60 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+func stopProcess(entry *Entry) (err error) {
+	pid, err := strconv.Atoi(entry.Value)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "journal: Invalid process id"),
+		}
+		return
+	}
+
+	output, e := utils.ExecOutput("/bin/ps", "-p", entry.Value, "-o", "comm=")
+	if e != nil {
+		return
+	}
+
+	if filepath.Base(strings.TrimSpace(output)) !=
+		filepath.Base(entry.Path) {
+
+		return
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		err = nil
+		return
+	}
+
+	err = proc.Signal(os.Interrupt)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "journal: Failed to stop process"),
+		}
+		return
+	}
+
+	return
+}
+
+func teardown(entry *Entry) (err error) {
+	switch entry.Type {
+	case ProcessEntry:
+		err = stopProcess(entry)
+	case FileEntry:
+		err = removeFile(entry)
+	}
+
+	return
+}
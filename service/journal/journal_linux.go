@@ -0,0 +1,152 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/dns"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/ipv6guard"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+func ifaceExists(iface string) bool {
+	if iface == "" {
+		return false
+	}
+
+	exists, _ := utils.Exists(filepath.Join("/sys/class/net", iface))
+	return exists
+}
+
+func stopProcess(entry *Entry) (err error) {
+	pid, err := strconv.Atoi(entry.Value)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "journal: Invalid process id"),
+		}
+		return
+	}
+
+	procPath := filepath.Join("/proc", entry.Value)
+
+	exe, e := os.Readlink(filepath.Join(procPath, "exe"))
+	if e != nil {
+		return
+	}
+
+	if filepath.Base(exe) != filepath.Base(entry.Path) {
+		return
+	}
+
+	err = syscall.Kill(pid, syscall.SIGTERM)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "journal: Failed to stop process"),
+		}
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+
+		exists, _ := utils.Exists(procPath)
+		if !exists {
+			return
+		}
+	}
+
+	err = syscall.Kill(pid, syscall.SIGKILL)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "journal: Failed to kill process"),
+		}
+		return
+	}
+
+	return
+}
+
+func removeWgIface(entry *Entry) (err error) {
+	if !ifaceExists(entry.Value) {
+		return
+	}
+
+	if entry.Path != "" {
+		_, e := utils.ExecCombinedOutputLogged(
+			[]string{
+				"does not exist",
+				"is not a",
+			},
+			entry.Path,
+			"down", entry.Value,
+		)
+		if e == nil && !ifaceExists(entry.Value) {
+			return
+		}
+	}
+
+	_, err = utils.ExecCombinedOutputLogged(
+		[]string{
+			"Cannot find device",
+		},
+		"ip", "link", "delete", "dev", entry.Value,
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func removeRoute(entry *Entry) (err error) {
+	if !ifaceExists(entry.Path) {
+		return
+	}
+
+	_, _ = utils.ExecCombinedOutput(
+		"ip", "route", "del", entry.Value, "dev", entry.Path)
+
+	return
+}
+
+func removeDns(entry *Entry) (err error) {
+	err = dns.Recover(entry.Value, entry.Path)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func removeIpv6Guard(entry *Entry) (err error) {
+	err = ipv6guard.Reset()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func teardown(entry *Entry) (err error) {
+	switch entry.Type {
+	case ProcessEntry:
+		err = stopProcess(entry)
+	case WgIfaceEntry:
+		err = removeWgIface(entry)
+	case RouteEntry:
+		err = removeRoute(entry)
+	case DnsEntry:
+		err = removeDns(entry)
+	case Ipv6GuardEntry:
+		err = removeIpv6Guard(entry)
+	case FileEntry:
+		err = removeFile(entry)
+	}
+
+	return
+}
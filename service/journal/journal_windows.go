@@ -0,0 +1,62 @@
+package journal
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"golang.org/x/sys/windows"
+)
+
+func stopProcess(entry *Entry) (err error) {
+	pid, err := strconv.Atoi(entry.Value)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "journal: Invalid process id"),
+		}
+		return
+	}
+
+	handle, e := windows.OpenProcess(
+		windows.PROCESS_QUERY_LIMITED_INFORMATION|windows.PROCESS_TERMINATE,
+		false, uint32(pid))
+	if e != nil {
+		return
+	}
+	defer windows.CloseHandle(handle)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+	e = windows.QueryFullProcessImageName(handle, 0, &buf[0], &size)
+	if e != nil {
+		return
+	}
+	exe := windows.UTF16ToString(buf[:size])
+
+	if !strings.EqualFold(filepath.Base(exe), filepath.Base(entry.Path)) {
+		return
+	}
+
+	err = windows.TerminateProcess(handle, 1)
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "journal: Failed to stop process"),
+		}
+		return
+	}
+
+	return
+}
+
+func teardown(entry *Entry) (err error) {
+	switch entry.Type {
+	case ProcessEntry:
+		err = stopProcess(entry)
+	case FileEntry:
+		err = removeFile(entry)
+	}
+
+	return
+}
@@ -0,0 +1,341 @@
+package killswitch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	profiles = map[string]*Profile{}
+	loaded   = false
+	lock     = sync.Mutex{}
+)
+
+// Profile stores the addresses of the remotes, sync hosts, proxies and
+// resolvers and the tunnel interfaces allowed while the kill switch of the
+// profile is active, the addresses of each host are kept to connect
+// without DNS during reconnects
+type Profile struct {
+	Id        string              `json:"id"`
+	Remotes   map[string][]string `json:"remotes"`
+	Ifaces    []string            `json:"ifaces"`
+	Timestamp time.Time           `json:"timestamp"`
+}
+
+type Rules struct {
+	Addrs4 []string
+	Addrs6 []string
+	Ifaces []string
+}
+
+func GetPath() string {
+	return filepath.Join(filepath.Dir(config.GetPath()),
+		"pritunl-killswitch.json")
+}
+
+func load() {
+	if loaded {
+		return
+	}
+	loaded = true
+
+	data, err := ioutil.ReadFile(GetPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "killswitch: Failed to read state"),
+			}
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("killswitch: Failed to load kill switch state")
+		}
+		return
+	}
+
+	loadedProfiles := map[string]*Profile{}
+	err = json.Unmarshal(data, &loadedProfiles)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "killswitch: Failed to parse state"),
+		}
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("killswitch: Failed to load kill switch state")
+		return
+	}
+
+	for prflId, prfl := range loadedProfiles {
+		if prfl != nil {
+			profiles[prflId] = prfl
+		}
+	}
+}
+
+func save() (err error) {
+	pth := GetPath()
+	tmpPth := pth + ".tmp"
+
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "killswitch: Failed to marshal state"),
+		}
+		return
+	}
+
+	err = utils.ExistsMkdir(filepath.Dir(pth), 0755)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(tmpPth, data, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "killswitch: Failed to write state"),
+		}
+		return
+	}
+
+	err = os.Rename(tmpPth, pth)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "killswitch: Failed to move state"),
+		}
+		return
+	}
+
+	return
+}
+
+// getRules merges the allowed addresses and interfaces of all profiles
+func getRules() (rules *Rules) {
+	rules = &Rules{
+		Addrs4: []string{},
+		Addrs6: []string{},
+		Ifaces: append([]string{}, tunnelIfaces...),
+	}
+
+	addrs := map[string]bool{}
+	ifaces := map[string]bool{}
+	for _, iface := range rules.Ifaces {
+		ifaces[iface] = true
+	}
+
+	for _, prfl := range profiles {
+		for _, remoteAddrs := range prfl.Remotes {
+			for _, addr := range remoteAddrs {
+				ip := net.ParseIP(addr)
+				if ip == nil || addrs[ip.String()] {
+					continue
+				}
+				addrs[ip.String()] = true
+
+				if ip.To4() != nil {
+					rules.Addrs4 = append(rules.Addrs4, ip.String())
+				} else {
+					rules.Addrs6 = append(rules.Addrs6, ip.String())
+				}
+			}
+		}
+
+		for _, iface := range prfl.Ifaces {
+			if iface == "" || ifaces[iface] {
+				continue
+			}
+			ifaces[iface] = true
+			rules.Ifaces = append(rules.Ifaces, iface)
+		}
+	}
+
+	sort.Strings(rules.Addrs4)
+	sort.Strings(rules.Addrs6)
+
+	return
+}
+
+func update() (err error) {
+	err = save()
+	if err != nil {
+		return
+	}
+
+	if len(profiles) == 0 {
+		err = removeRules()
+		if err != nil {
+			return
+		}
+		return
+	}
+
+	err = applyRules(getRules())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Enable installs or updates the kill switch of the profile, the remote
+// addresses are merged with the addresses stored from earlier connections
+func Enable(prflId string, remotes map[string][]string,
+	ifaces []string) (err error) {
+
+	if !Supported {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+		}).Warn("killswitch: Kill switch not supported on platform")
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	prfl := profiles[prflId]
+	if prfl == nil {
+		prfl = &Profile{
+			Id:      prflId,
+			Remotes: map[string][]string{},
+			Ifaces:  []string{},
+		}
+		profiles[prflId] = prfl
+
+		logrus.WithFields(logrus.Fields{
+			"profile_id": prflId,
+		}).Info("killswitch: Enabling kill switch")
+	}
+
+	for host, addrs := range remotes {
+		if len(addrs) > 0 {
+			prfl.Remotes[host] = addrs
+		}
+	}
+	for _, iface := range ifaces {
+		if iface == "" {
+			continue
+		}
+
+		exists := false
+		for _, prflIface := range prfl.Ifaces {
+			if prflIface == iface {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			prfl.Ifaces = append(prfl.Ifaces, iface)
+		}
+	}
+	prfl.Timestamp = time.Now()
+
+	err = update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Disable removes the kill switch of the profile, the rules are removed
+// when no other profile has an active kill switch
+func Disable(prflId string) (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	if profiles[prflId] == nil {
+		return
+	}
+	delete(profiles, prflId)
+
+	logrus.WithFields(logrus.Fields{
+		"profile_id": prflId,
+	}).Info("killswitch: Disabling kill switch")
+
+	err = update()
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func IsActive(prflId string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	return profiles[prflId] != nil
+}
+
+// GetAddrs returns the stored addresses of the remote host
+func GetAddrs(prflId, host string) (addrs []string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	prfl := profiles[prflId]
+	if prfl == nil {
+		return
+	}
+
+	addrs = append([]string{}, prfl.Remotes[host]...)
+
+	return
+}
+
+// Recover restores the rules after the service restarts, profiles that
+// should no longer be protected are removed, must be called on startup
+// before any connections are started
+func Recover(keep func(prflId string) bool) (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	load()
+
+	if len(profiles) == 0 {
+		return
+	}
+
+	for prflId := range profiles {
+		if !keep(prflId) {
+			logrus.WithFields(logrus.Fields{
+				"profile_id": prflId,
+			}).Info("killswitch: Removing kill switch from previous run")
+			delete(profiles, prflId)
+		}
+	}
+
+	if len(profiles) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"profiles": len(profiles),
+		}).Warn("killswitch: Restoring kill switch from previous run")
+	}
+
+	if !Supported {
+		return
+	}
+
+	err = update()
+	if err != nil {
+		return
+	}
+
+	return
+}
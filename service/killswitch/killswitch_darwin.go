@@ -0,0 +1,13 @@
+package killswitch
+
+const Supported = false
+
+var tunnelIfaces = []string{}
+
+func applyRules(rules *Rules) (err error) {
+	return
+}
+
+func removeRules() (err error) {
+	return
+}
@@ -0,0 +1,94 @@
+package killswitch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+)
+
+const (
+	Supported = true
+	tableName = "pritunl_killswitch"
+)
+
+var tunnelIfaces = []string{
+	"tun*",
+	"wg*",
+}
+
+func formatSet(vals []string) string {
+	return "{ " + strings.Join(vals, ", ") + " }"
+}
+
+func getScript(rules *Rules) string {
+	script := fmt.Sprintf("table inet %s\n", tableName)
+	script += fmt.Sprintf("delete table inet %s\n", tableName)
+	script += fmt.Sprintf("table inet %s {\n", tableName)
+
+	script += "\tchain output {\n"
+	script += "\t\ttype filter hook output priority 0; policy drop;\n"
+	script += "\t\toifname \"lo\" accept\n"
+	for _, iface := range rules.Ifaces {
+		script += fmt.Sprintf("\t\toifname \"%s\" accept\n", iface)
+	}
+	script += "\t\tudp sport 68 udp dport 67 accept\n"
+	script += "\t\tudp sport 546 udp dport 547 accept\n"
+	script += "\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, " +
+		"nd-neighbor-advert } accept\n"
+	if len(rules.Addrs4) > 0 {
+		script += fmt.Sprintf("\t\tip daddr %s accept\n",
+			formatSet(rules.Addrs4))
+	}
+	if len(rules.Addrs6) > 0 {
+		script += fmt.Sprintf("\t\tip6 daddr %s accept\n",
+			formatSet(rules.Addrs6))
+	}
+	script += "\t}\n"
+
+	script += "\tchain input {\n"
+	script += "\t\ttype filter hook input priority 0; policy drop;\n"
+	script += "\t\tiifname \"lo\" accept\n"
+	for _, iface := range rules.Ifaces {
+		script += fmt.Sprintf("\t\tiifname \"%s\" accept\n", iface)
+	}
+	script += "\t\tct state established,related accept\n"
+	script += "\t\tudp sport 67 udp dport 68 accept\n"
+	script += "\t\tudp sport 547 udp dport 546 accept\n"
+	script += "\t\ticmpv6 type { nd-router-advert, nd-neighbor-solicit, " +
+		"nd-neighbor-advert } accept\n"
+	script += "\t}\n"
+
+	script += "}\n"
+
+	return script
+}
+
+func applyRules(rules *Rules) (err error) {
+	_, err = utils.ExecInputOutput(getScript(rules), "nft", "-f", "-")
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "killswitch: Failed to apply nftables rules"),
+		}
+		return
+	}
+
+	return
+}
+
+func removeRules() (err error) {
+	script := fmt.Sprintf("table inet %s\ndelete table inet %s\n",
+		tableName, tableName)
+
+	_, err = utils.ExecInputOutput(script, "nft", "-f", "-")
+	if err != nil {
+		err = &errortypes.ExecError{
+			errors.Wrap(err, "killswitch: Failed to remove nftables rules"),
+		}
+		return
+	}
+
+	return
+}
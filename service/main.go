@@ -19,6 +19,9 @@ import (
 	"github.com/pritunl/pritunl-client-electron/service/connection"
 	"github.com/pritunl/pritunl-client-electron/service/constants"
 	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/health"
+	"github.com/pritunl/pritunl-client-electron/service/history"
+	"github.com/pritunl/pritunl-client-electron/service/journal"
 	"github.com/pritunl/pritunl-client-electron/service/logger"
 	"github.com/pritunl/pritunl-client-electron/service/router"
 	"github.com/pritunl/pritunl-client-electron/service/setup"
@@ -115,6 +118,13 @@ func main() {
 		panic(err)
 	}
 
+	err = auth.LoadTokens()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("main: Failed to load api tokens")
+	}
+
 	err = autoclean.CheckAndClean()
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -141,6 +151,24 @@ func main() {
 
 	watch.StartWatch()
 
+	err = journal.Replay()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("main: Failed to replay network journal")
+		err = nil
+	}
+
+	if runtime.GOOS == "linux" {
+		err = connection.RecoverKillSwitch()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("main: Failed to recover kill switch")
+			err = nil
+		}
+	}
+
 	err = connection.Clean()
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -233,6 +261,10 @@ func main() {
 		conn.StopWait()
 	}
 
+	watch.StopDnsStub()
+	health.Flush()
+	history.Flush()
+
 	if runtime.GOOS == "darwin" {
 		_ = utils.ClearScutilConnKeys()
 		_ = utils.RestoreScutilDns(true)
@@ -10,10 +10,24 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Proxy is exported as a http-proxy or socks-proxy directive, AuthPath is
+// an optional file with the proxy username and password
+type Proxy struct {
+	Type     string
+	Host     string
+	Port     int
+	AuthPath string
+}
+
 type Remote struct {
 	Host  string
 	Port  int
 	Proto string
+	Proxy *Proxy
+}
+
+func (r *Remote) IsTcp() bool {
+	return strings.HasPrefix(r.Proto, "tcp")
 }
 
 func (r *Remote) GetFormatted() string {
@@ -93,13 +107,62 @@ func (o *Ovpn) Export(chown string) string {
 	output += fmt.Sprintf("dev %s\n", o.Dev)
 	output += fmt.Sprintf("dev-type %s\n", o.DevType)
 	output += "connect-retry-max 1\n"
+
+	hasProxy := false
 	for _, remote := range o.Remotes {
+		if remote.Proxy != nil {
+			hasProxy = true
+			break
+		}
+	}
+
+	for _, remote := range o.Remotes {
+		if hasProxy {
+			output += "<connection>\n"
+		}
+
 		output += fmt.Sprintf(
 			"remote %s %d %s\n",
 			remote.Host,
 			remote.Port,
 			remote.Proto,
 		)
+
+		if remote.Proxy != nil {
+			directive := "http-proxy"
+			if remote.Proxy.Type == "socks" {
+				directive = "socks-proxy"
+			}
+
+			if remote.Proxy.AuthPath != "" && directive == "http-proxy" {
+				output += fmt.Sprintf(
+					"%s %s %d %s basic\n",
+					directive,
+					remote.Proxy.Host,
+					remote.Proxy.Port,
+					remote.Proxy.AuthPath,
+				)
+			} else if remote.Proxy.AuthPath != "" {
+				output += fmt.Sprintf(
+					"%s %s %d %s\n",
+					directive,
+					remote.Proxy.Host,
+					remote.Proxy.Port,
+					remote.Proxy.AuthPath,
+				)
+			} else {
+				output += fmt.Sprintf(
+					"%s %s %d\n",
+					directive,
+					remote.Proxy.Host,
+					remote.Proxy.Port,
+				)
+			}
+		}
+
+		if hasProxy {
+			output += "</connection>\n"
+		}
 	}
 	if o.NoBind {
 		output += "nobind\n"
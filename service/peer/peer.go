@@ -0,0 +1,69 @@
+package peer
+
+import (
+	"context"
+	"net"
+	"os/user"
+	"strconv"
+
+	"github.com/dropbox/godropbox/container/set"
+)
+
+type contextKey struct{}
+
+type socketKey struct{}
+
+type Creds struct {
+	Pid int
+	Uid int
+	Gid int
+}
+
+// Groups returns the primary and supplementary group IDs of the peer user
+func (c *Creds) Groups() (gids set.Set) {
+	gids = set.NewSet()
+	gids.Add(c.Gid)
+
+	usr, err := user.LookupId(strconv.Itoa(c.Uid))
+	if err != nil {
+		return
+	}
+
+	groupIds, err := usr.GroupIds()
+	if err != nil {
+		return
+	}
+
+	for _, groupId := range groupIds {
+		gid, e := strconv.Atoi(groupId)
+		if e != nil {
+			continue
+		}
+		gids.Add(gid)
+	}
+
+	return
+}
+
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if _, ok := conn.(*net.UnixConn); ok {
+		ctx = context.WithValue(ctx, socketKey{}, true)
+	}
+
+	creds := getCreds(conn)
+	if creds == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextKey{}, creds)
+}
+
+func IsSocket(ctx context.Context) bool {
+	socket, _ := ctx.Value(socketKey{}).(bool)
+	return socket
+}
+
+func FromContext(ctx context.Context) *Creds {
+	creds, _ := ctx.Value(contextKey{}).(*Creds)
+	return creds
+}
@@ -0,0 +1,59 @@
+package peer
+
+import (
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const Supported = true
+
+func getCreds(conn net.Conn) (creds *Creds) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	var xucred *unix.Xucred
+	var pid int
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		xucred, credErr = unix.GetsockoptXucred(
+			int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if credErr != nil {
+			return
+		}
+
+		pid, credErr = unix.GetsockoptInt(
+			int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("peer: Failed to read socket peer credentials")
+		return
+	}
+
+	// The first group of the credentials is the effective group
+	gid := -1
+	if xucred.Ngroups > 0 {
+		gid = int(xucred.Groups[0])
+	}
+
+	creds = &Creds{
+		Pid: pid,
+		Uid: int(xucred.Uid),
+		Gid: gid,
+	}
+
+	return
+}
@@ -0,0 +1,46 @@
+package peer
+
+import (
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const Supported = true
+
+func getCreds(conn net.Conn) (creds *Creds) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(
+			int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err == nil {
+		err = credErr
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("peer: Failed to read socket peer credentials")
+		return
+	}
+
+	creds = &Creds{
+		Pid: int(ucred.Pid),
+		Uid: int(ucred.Uid),
+		Gid: int(ucred.Gid),
+	}
+
+	return
+}
@@ -0,0 +1,11 @@
+package peer
+
+import (
+	"net"
+)
+
+const Supported = false
+
+func getCreds(conn net.Conn) (creds *Creds) {
+	return
+}
@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/types"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HttpProxy  = "http"
+	SocksProxy = "socks"
+)
+
+type profileKey struct{}
+
+func init() {
+	utils.ProxyFunc = Func
+}
+
+// Server is a parsed proxy used for OpenVPN directives, Secure is set for
+// https proxies which OpenVPN does not support
+type Server struct {
+	Type     string
+	Host     string
+	Port     int
+	Secure   bool
+	Username string
+	Password string
+}
+
+// WithProfile returns a context that uses the profile proxy settings for
+// requests instead of the global settings
+func WithProfile(ctx context.Context, prxy *types.Proxy) context.Context {
+	if prxy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, profileKey{}, prxy)
+}
+
+// GetSettings returns the profile settings if set otherwise the global
+// settings
+func GetSettings(prxy *types.Proxy) *types.Proxy {
+	if prxy != nil {
+		return prxy
+	}
+	return config.Config.Proxy
+}
+
+func matchNoProxy(noProxy []string, host string) bool {
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if entry == "*" {
+			return true
+		}
+
+		if ip != nil {
+			if strings.Contains(entry, "/") {
+				_, network, err := net.ParseCIDR(entry)
+				if err == nil && network.Contains(ip) {
+					return true
+				}
+				continue
+			}
+
+			entryIp := net.ParseIP(strings.Trim(entry, "[]"))
+			if entryIp != nil && entryIp.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entry = strings.TrimPrefix(entry, "*")
+		if strings.HasPrefix(entry, ".") {
+			if strings.HasSuffix(host, entry) || host == entry[1:] {
+				return true
+			}
+		} else if host == entry {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseUrl(rawUrl string) (u *url.URL, err error) {
+	u, err = url.Parse(rawUrl)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "proxy: Failed to parse proxy url"),
+		}
+		return
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("proxy: Unsupported proxy scheme '%s'", u.Scheme),
+		}
+		return
+	}
+
+	return
+}
+
+// GetUrl returns the proxy url for the host or nil if the host should be
+// connected directly
+func GetUrl(prxy *types.Proxy, host string) (u *url.URL, err error) {
+	prxy = GetSettings(prxy)
+	if prxy == nil {
+		return
+	}
+
+	if prxy.Url == "" {
+		if prxy.UseEnv {
+			req := &http.Request{
+				URL: &url.URL{
+					Scheme: "https",
+					Host:   host,
+				},
+			}
+			u, err = http.ProxyFromEnvironment(req)
+			if err != nil {
+				err = &errortypes.ParseError{
+					errors.Wrap(err, "proxy: Failed to parse env proxy"),
+				}
+				return
+			}
+		}
+		if u == nil {
+			return
+		}
+	} else {
+		u, err = parseUrl(prxy.Url)
+		if err != nil {
+			return
+		}
+	}
+
+	hostname := host
+	h, _, e := net.SplitHostPort(host)
+	if e == nil {
+		hostname = h
+	}
+
+	if matchNoProxy(prxy.NoProxy, hostname) {
+		u = nil
+		return
+	}
+
+	return
+}
+
+// Func is the proxy function for http transports, the profile settings
+// are read from the request context
+func Func(req *http.Request) (u *url.URL, err error) {
+	prxy, _ := req.Context().Value(profileKey{}).(*types.Proxy)
+
+	u, err = GetUrl(prxy, req.URL.Host)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"host":  req.URL.Host,
+			"error": err,
+		}).Error("proxy: Failed to get proxy")
+		return
+	}
+
+	return
+}
+
+// IsActive returns true if requests to the host will use a proxy
+func IsActive(prxy *types.Proxy, host string) bool {
+	u, err := GetUrl(prxy, host)
+	return err == nil && u != nil
+}
+
+// GetServer returns the proxy server for the host or nil if the host
+// should be connected directly
+func GetServer(prxy *types.Proxy, host string) (srv *Server, err error) {
+	u, err := GetUrl(prxy, host)
+	if err != nil || u == nil {
+		return
+	}
+
+	srv = &Server{
+		Host: u.Hostname(),
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		srv.Type = SocksProxy
+		srv.Port = 1080
+	case "https":
+		srv.Type = HttpProxy
+		srv.Port = 443
+		srv.Secure = true
+	default:
+		srv.Type = HttpProxy
+		srv.Port = 8080
+	}
+
+	if u.Port() != "" {
+		srv.Port, err = strconv.Atoi(u.Port())
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "proxy: Invalid proxy port"),
+			}
+			srv = nil
+			return
+		}
+	}
+
+	if u.User != nil {
+		srv.Username = u.User.Username()
+		srv.Password, _ = u.User.Password()
+	}
+
+	return
+}
@@ -0,0 +1,316 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+)
+
+var dohTransport = &http.Transport{
+	TLSHandshakeTimeout: 5 * time.Second,
+	TLSClientConfig: &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	},
+}
+
+var dohClient = &http.Client{
+	Transport: dohTransport,
+}
+
+func init() {
+	dohTransport.DialContext = dohDial
+}
+
+// dohDial connects to the DoH server without the system resolver, servers
+// with a hostname are resolved with the configured DoT servers and
+// nameservers
+func dohDial(ctx context.Context, network, addr string) (
+	conn net.Conn, err error) {
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "resolver: Failed to parse DoH address"),
+		}
+		return
+	}
+
+	if net.ParseIP(host) != nil {
+		conn, err = dialer.DialContext(ctx, network, addr)
+		return
+	}
+
+	conf := config.Config.Resolver
+	servers := []*server{}
+	if conf != nil {
+		for _, srv := range getServers(conf) {
+			if srv.typ != "doh" {
+				servers = append(servers, srv)
+			}
+		}
+	}
+
+	if len(servers) == 0 {
+		err = &errortypes.ParseError{
+			errors.Newf("resolver: DoH server '%s' requires an IP "+
+				"address or a DoT server or nameserver to bootstrap", host),
+		}
+		return
+	}
+
+	timeout := getTimeout(conf)
+	for _, srv := range servers {
+		ips, e := lookupServer(srv, host, timeout)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		for _, ip := range ips {
+			conn, err = dialer.DialContext(ctx, network,
+				net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return
+			}
+		}
+	}
+
+	if err == nil {
+		err = &errortypes.RequestError{
+			errors.Newf("resolver: No addresses found for '%s'", host),
+		}
+	}
+
+	return
+}
+
+type dohAddr struct{}
+
+func (d dohAddr) Network() string {
+	return "doh"
+}
+
+func (d dohAddr) String() string {
+	return "doh"
+}
+
+// dohConn implements a stream connection for the resolver, each length
+// prefixed query written is sent as a DNS-over-HTTPS request and the
+// length prefixed response is returned on read
+type dohConn struct {
+	ctx      context.Context
+	addr     string
+	timeout  time.Duration
+	lock     sync.Mutex
+	cond     *sync.Cond
+	writeBuf bytes.Buffer
+	readBuf  bytes.Buffer
+	err      error
+	closed   bool
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newDohConn(ctx context.Context, addr string,
+	timeout time.Duration) (conn *dohConn) {
+
+	conn = &dohConn{
+		ctx:     ctx,
+		addr:    addr,
+		timeout: timeout,
+	}
+	conn.cond = sync.NewCond(&conn.lock)
+
+	return
+}
+
+func (c *dohConn) query(msg []byte) (resp []byte, err error) {
+	u, err := parseDohUrl(c.addr)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		u.String(),
+		bytes.NewReader(msg),
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "resolver: DoH request error"),
+		}
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := dohClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "resolver: DoH request error"),
+		}
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("resolver: DoH bad status %d", res.StatusCode),
+		}
+		return
+	}
+
+	resp, err = ioutil.ReadAll(io.LimitReader(res.Body, 65535))
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "resolver: DoH read error"),
+		}
+		return
+	}
+
+	return
+}
+
+func (c *dohConn) Write(b []byte) (n int, err error) {
+	c.lock.Lock()
+	c.writeBuf.Write(b)
+	n = len(b)
+
+	msgs := [][]byte{}
+	for c.writeBuf.Len() >= 2 {
+		buf := c.writeBuf.Bytes()
+		msgLen := int(binary.BigEndian.Uint16(buf[:2]))
+		if len(buf) < 2+msgLen {
+			break
+		}
+
+		msg := make([]byte, msgLen)
+		copy(msg, buf[2:2+msgLen])
+		c.writeBuf.Next(2 + msgLen)
+		msgs = append(msgs, msg)
+	}
+	c.lock.Unlock()
+
+	for _, msg := range msgs {
+		go func(msg []byte) {
+			resp, e := c.query(msg)
+
+			c.lock.Lock()
+			if e != nil {
+				c.err = e
+			} else {
+				prefix := make([]byte, 2)
+				binary.BigEndian.PutUint16(prefix, uint16(len(resp)))
+				c.readBuf.Write(prefix)
+				c.readBuf.Write(resp)
+			}
+			c.cond.Broadcast()
+			c.lock.Unlock()
+		}(msg)
+	}
+
+	return
+}
+
+func (c *dohConn) Read(b []byte) (n int, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for c.readBuf.Len() == 0 && c.err == nil && !c.closed &&
+		!c.isExpired() {
+
+		c.cond.Wait()
+	}
+
+	if c.readBuf.Len() > 0 {
+		n, err = c.readBuf.Read(b)
+		return
+	}
+
+	if c.err != nil {
+		err = c.err
+		return
+	}
+
+	if c.isExpired() {
+		err = os.ErrDeadlineExceeded
+		return
+	}
+
+	err = io.EOF
+	return
+}
+
+func (c *dohConn) isExpired() bool {
+	return !c.deadline.IsZero() && !time.Now().Before(c.deadline)
+}
+
+func (c *dohConn) Close() error {
+	c.lock.Lock()
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.cond.Broadcast()
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *dohConn) LocalAddr() net.Addr {
+	return dohAddr{}
+}
+
+func (c *dohConn) RemoteAddr() net.Addr {
+	return dohAddr{}
+}
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline wakes blocked reads when the deadline is reached, the
+// pending queries are bounded by the resolver timeout
+func (c *dohConn) SetReadDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.deadline = t
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	if !t.IsZero() {
+		c.timer = time.AfterFunc(time.Until(t), func() {
+			c.lock.Lock()
+			c.cond.Broadcast()
+			c.lock.Unlock()
+		})
+	}
+	c.cond.Broadcast()
+
+	return nil
+}
+
+// SetWriteDeadline is not used, writes do not block
+func (c *dohConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
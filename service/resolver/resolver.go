@@ -0,0 +1,265 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	dnsPort        = "53"
+	dotPort        = "853"
+)
+
+var dialer = &net.Dialer{
+	Timeout:   10 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+type server struct {
+	typ  string
+	addr string
+}
+
+func getTimeout(conf *config.ResolverConfig) time.Duration {
+	if conf.Timeout > 0 {
+		return time.Duration(conf.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+func getServers(conf *config.ResolverConfig) (servers []*server) {
+	servers = []*server{}
+
+	for _, addr := range conf.DohServers {
+		servers = append(servers, &server{
+			typ:  "doh",
+			addr: addr,
+		})
+	}
+	for _, addr := range conf.DotServers {
+		servers = append(servers, &server{
+			typ:  "dot",
+			addr: addr,
+		})
+	}
+	for _, addr := range conf.Nameservers {
+		servers = append(servers, &server{
+			typ:  "dns",
+			addr: addr,
+		})
+	}
+
+	return
+}
+
+func withPort(addr, port string) string {
+	_, _, err := net.SplitHostPort(addr)
+	if err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
+
+// newResolver returns a resolver that sends all queries to the server
+func newResolver(srv *server, timeout time.Duration) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (
+			conn net.Conn, err error) {
+
+			switch srv.typ {
+			case "doh":
+				conn = newDohConn(ctx, srv.addr, timeout)
+			case "dot":
+				addr := withPort(srv.addr, dotPort)
+				serverName, _, _ := net.SplitHostPort(addr)
+
+				conn, err = (&tls.Dialer{
+					NetDialer: dialer,
+					Config: &tls.Config{
+						ServerName: serverName,
+						MinVersion: tls.VersionTLS12,
+					},
+				}).DialContext(ctx, "tcp", addr)
+			default:
+				conn, err = dialer.DialContext(
+					ctx, network, withPort(srv.addr, dnsPort))
+			}
+
+			return
+		},
+	}
+}
+
+func lookupServer(srv *server, host string, timeout time.Duration) (
+	ips []net.IP, err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Fully qualified name to skip the search domains of resolv.conf
+	fqdn := host
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+
+	addrs, err := newResolver(srv, timeout).LookupIPAddr(ctx, fqdn)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "resolver: Lookup failed"),
+		}
+		return
+	}
+
+	ips = []net.IP{}
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+
+	return
+}
+
+// GetHosts returns the hosts of the configured resolvers
+func GetHosts() (hosts []string) {
+	hosts = []string{}
+
+	conf := config.Config.Resolver
+	if conf == nil {
+		return
+	}
+
+	for _, srv := range getServers(conf) {
+		if srv.typ == "doh" {
+			u, err := parseDohUrl(srv.addr)
+			if err == nil && u.Hostname() != "" {
+				hosts = append(hosts, u.Hostname())
+			}
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(withPort(srv.addr, dnsPort))
+		if err == nil && host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return
+}
+
+// LookupIP resolves the host with the configured resolvers in order of
+// DNS-over-HTTPS, DNS-over-TLS then nameservers. The system resolver is
+// used when no resolver is configured or when all resolvers fail and the
+// system fallback is enabled.
+func LookupIP(host string) (ips []net.IP, err error) {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		ips = []net.IP{ip}
+		return
+	}
+
+	conf := config.Config.Resolver
+	servers := []*server{}
+	if conf != nil {
+		servers = getServers(conf)
+	}
+
+	if len(servers) == 0 {
+		ips, err = net.LookupIP(host)
+		return
+	}
+
+	timeout := getTimeout(conf)
+	for _, srv := range servers {
+		ips, err = lookupServer(srv, host, timeout)
+		if err == nil && len(ips) > 0 {
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"host":            host,
+			"resolver_type":   srv.typ,
+			"resolver_server": srv.addr,
+			"error":           err,
+		}).Warn("resolver: Failed to resolve host with resolver")
+	}
+
+	if conf.SystemFallback {
+		ips, err = net.LookupIP(host)
+		return
+	}
+
+	if err == nil {
+		err = &errortypes.RequestError{
+			errors.Newf("resolver: No addresses found for '%s'", host),
+		}
+	}
+
+	return
+}
+
+// DialContext dials the address after resolving the host with LookupIP,
+// each resolved address is attempted in order
+func DialContext(ctx context.Context, network, addr string) (
+	conn net.Conn, err error) {
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "resolver: Failed to parse address"),
+		}
+		return
+	}
+
+	if net.ParseIP(host) != nil || config.Config.Resolver == nil {
+		conn, err = dialer.DialContext(ctx, network, addr)
+		return
+	}
+
+	ips, err := LookupIP(host)
+	if err != nil {
+		return
+	}
+
+	for _, ip := range ips {
+		conn, err = dialer.DialContext(ctx, network,
+			net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+func parseDohUrl(addr string) (u *url.URL, err error) {
+	u, err = url.Parse(addr)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "resolver: Failed to parse DoH URL"),
+		}
+		return
+	}
+
+	if u.Scheme != "https" {
+		err = &errortypes.ParseError{
+			errors.New("resolver: DoH URL must use https"),
+		}
+		return
+	}
+
+	if u.Path == "" {
+		u.Path = "/dns-query"
+	}
+
+	return
+}
@@ -10,8 +10,11 @@ import (
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
 	"github.com/pritunl/pritunl-client-electron/service/handlers"
+	"github.com/pritunl/pritunl-client-electron/service/peer"
+	"github.com/sirupsen/logrus"
 )
 
 type Router struct {
@@ -92,6 +95,11 @@ func (r *Router) Shutdown() {
 }
 
 func (r *Router) Init() {
+	if config.GetAccessPolicy() != nil && !peer.Supported {
+		logrus.Warn("router: Access policy is not supported " +
+			"on this platform, ignoring policy")
+	}
+
 	router := gin.New()
 	handlers.Register(router)
 
@@ -101,6 +109,7 @@ func (r *Router) Init() {
 		ReadTimeout:    300 * time.Second,
 		WriteTimeout:   300 * time.Second,
 		MaxHeaderBytes: 4096,
+		ConnContext:    peer.ConnContext,
 	}
 
 	return
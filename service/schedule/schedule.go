@@ -0,0 +1,293 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/types"
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultWarning = 300
+
+var (
+	overrides     = map[string]time.Time{}
+	overridesLock = sync.Mutex{}
+	warnings      = map[string]time.Time{}
+	warningsLock  = sync.Mutex{}
+	weekdays      = map[string]time.Weekday{
+		"sun":       time.Sunday,
+		"sunday":    time.Sunday,
+		"mon":       time.Monday,
+		"monday":    time.Monday,
+		"tue":       time.Tuesday,
+		"tuesday":   time.Tuesday,
+		"wed":       time.Wednesday,
+		"wednesday": time.Wednesday,
+		"thu":       time.Thursday,
+		"thursday":  time.Thursday,
+		"fri":       time.Friday,
+		"friday":    time.Friday,
+		"sat":       time.Saturday,
+		"saturday":  time.Saturday,
+	}
+)
+
+func IsSet(sched *types.Schedule) bool {
+	return sched != nil && len(sched.Windows) > 0
+}
+
+func getLocation(sched *types.Schedule) *time.Location {
+	if sched.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(sched.Timezone)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"timezone": sched.Timezone,
+			"error":    err,
+		}).Error("schedule: Failed to load timezone, using local")
+		return time.Local
+	}
+
+	return loc
+}
+
+func parseClock(val string) (hour, minute int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(val), ":")
+	if len(parts) != 2 {
+		return
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return
+	}
+
+	minute, err = strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 ||
+		(hour == 24 && minute != 0) {
+
+		return
+	}
+
+	ok = true
+	return
+}
+
+// Validate checks the timezone, window clocks and day names of the
+// schedule
+func Validate(sched *types.Schedule) (err error) {
+	if sched == nil {
+		return
+	}
+
+	if sched.Timezone != "" {
+		_, err = time.LoadLocation(sched.Timezone)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrapf(err, "schedule: Invalid timezone '%s'",
+					sched.Timezone),
+			}
+			return
+		}
+	}
+
+	if sched.Warning < 0 {
+		err = &errortypes.ParseError{
+			errors.New("schedule: Invalid warning"),
+		}
+		return
+	}
+
+	for _, window := range sched.Windows {
+		_, _, ok := parseClock(window.Start)
+		if !ok {
+			err = &errortypes.ParseError{
+				errors.Newf("schedule: Invalid window start '%s'",
+					window.Start),
+			}
+			return
+		}
+
+		_, _, ok = parseClock(window.End)
+		if !ok {
+			err = &errortypes.ParseError{
+				errors.Newf("schedule: Invalid window end '%s'",
+					window.End),
+			}
+			return
+		}
+
+		for _, name := range window.Days {
+			_, ok = weekdays[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				err = &errortypes.ParseError{
+					errors.Newf("schedule: Invalid window day '%s'", name),
+				}
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func matchDay(window types.ScheduleWindow, day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	for _, name := range window.Days {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if ok && weekday == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// windowEnd returns the latest end of the windows active at the time, the
+// windows starting on the previous day are included for overnight windows
+func windowEnd(sched *types.Schedule, now time.Time) (
+	active bool, end time.Time) {
+
+	for _, window := range sched.Windows {
+		startHour, startMin, ok := parseClock(window.Start)
+		if !ok {
+			continue
+		}
+		endHour, endMin, ok := parseClock(window.End)
+		if !ok {
+			continue
+		}
+
+		for _, offset := range []int{0, -1} {
+			day := time.Date(now.Year(), now.Month(), now.Day()+offset,
+				0, 0, 0, 0, now.Location())
+			if !matchDay(window, day.Weekday()) {
+				continue
+			}
+
+			start := time.Date(day.Year(), day.Month(), day.Day(),
+				startHour, startMin, 0, 0, now.Location())
+			stop := time.Date(day.Year(), day.Month(), day.Day(),
+				endHour, endMin, 0, 0, now.Location())
+			if !stop.After(start) {
+				stop = time.Date(day.Year(), day.Month(), day.Day()+1,
+					endHour, endMin, 0, 0, now.Location())
+			}
+
+			if !now.Before(start) && now.Before(stop) {
+				active = true
+				if stop.After(end) {
+					end = stop
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Window returns true if the time is within a window and the time the
+// window closes, adjacent windows are joined
+func Window(sched *types.Schedule, now time.Time) (
+	active bool, end time.Time) {
+
+	if !IsSet(sched) {
+		return
+	}
+
+	now = now.In(getLocation(sched))
+
+	active, end = windowEnd(sched, now)
+	if !active {
+		return
+	}
+
+	for i := 0; i < 14; i++ {
+		nextActive, nextEnd := windowEnd(sched, end)
+		if !nextActive || !nextEnd.After(end) {
+			break
+		}
+		end = nextEnd
+	}
+
+	return
+}
+
+func GetWarning(sched *types.Schedule) time.Duration {
+	if sched.Warning > 0 {
+		return time.Duration(sched.Warning) * time.Second
+	}
+	return DefaultWarning * time.Second
+}
+
+// SetOverride ignores the schedule for the profile until the end of the
+// current day in the schedule timezone
+func SetOverride(prflId string, sched *types.Schedule) {
+	if !IsSet(sched) {
+		return
+	}
+
+	now := time.Now().In(getLocation(sched))
+	until := time.Date(now.Year(), now.Month(), now.Day()+1,
+		0, 0, 0, 0, now.Location())
+
+	logrus.WithFields(logrus.Fields{
+		"profile_id": prflId,
+		"until":      until,
+	}).Info("schedule: Schedule overridden")
+
+	overridesLock.Lock()
+	overrides[prflId] = until
+	overridesLock.Unlock()
+}
+
+func IsOverride(prflId string) bool {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+
+	until, ok := overrides[prflId]
+	if !ok {
+		return false
+	}
+
+	if !time.Now().Before(until) {
+		delete(overrides, prflId)
+		return false
+	}
+
+	return true
+}
+
+func ClearOverride(prflId string) {
+	overridesLock.Lock()
+	delete(overrides, prflId)
+	overridesLock.Unlock()
+}
+
+// ShouldWarn returns true once for each window end when the end is within
+// the warning duration
+func ShouldWarn(prflId string, sched *types.Schedule, end time.Time) bool {
+	if time.Until(end) > GetWarning(sched) {
+		return false
+	}
+
+	warningsLock.Lock()
+	defer warningsLock.Unlock()
+
+	if warnings[prflId].Equal(end) {
+		return false
+	}
+	warnings[prflId] = end
+
+	return true
+}
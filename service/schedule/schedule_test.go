@@ -0,0 +1,362 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/pritunl/pritunl-client-electron/service/types"
+)
+
+const testTimezone = "America/New_York"
+
+func TestWindow(t *testing.T) {
+	loc, err := time.LoadLocation(testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		windows []types.ScheduleWindow
+		now     time.Time
+		active  bool
+		end     time.Time
+	}{
+		{
+			name:    "unset",
+			windows: []types.ScheduleWindow{},
+			now:     at(time.October, 14, 12, 0),
+			active:  false,
+		},
+		{
+			name: "inside",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 12, 0),
+			active: true,
+			end:    at(time.October, 14, 17, 0),
+		},
+		{
+			name: "before_start",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 8, 59),
+			active: false,
+		},
+		{
+			name: "at_start",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 9, 0),
+			active: true,
+			end:    at(time.October, 14, 17, 0),
+		},
+		{
+			name: "at_end",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 17, 0),
+			active: false,
+		},
+		{
+			name: "other_day",
+			windows: []types.ScheduleWindow{
+				{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 12, 0),
+			active: false,
+		},
+		{
+			name: "day_name",
+			windows: []types.ScheduleWindow{
+				{Days: []string{" Wednesday "}, Start: "09:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 12, 0),
+			active: true,
+			end:    at(time.October, 14, 17, 0),
+		},
+		{
+			name: "overnight_start",
+			windows: []types.ScheduleWindow{
+				{Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.October, 14, 23, 0),
+			active: true,
+			end:    at(time.October, 15, 6, 0),
+		},
+		{
+			name: "overnight_end",
+			windows: []types.ScheduleWindow{
+				{Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.October, 15, 2, 0),
+			active: true,
+			end:    at(time.October, 15, 6, 0),
+		},
+		{
+			name: "overnight_previous_day",
+			windows: []types.ScheduleWindow{
+				{Days: []string{"fri"}, Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.October, 17, 2, 0),
+			active: true,
+			end:    at(time.October, 17, 6, 0),
+		},
+		{
+			name: "overnight_not_previous_day",
+			windows: []types.ScheduleWindow{
+				{Days: []string{"fri"}, Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.October, 16, 2, 0),
+			active: false,
+		},
+		{
+			name: "adjacent",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "12:00"},
+				{Start: "12:00", End: "17:00"},
+			},
+			now:    at(time.October, 14, 10, 0),
+			active: true,
+			end:    at(time.October, 14, 17, 0),
+		},
+		{
+			name: "overlapping",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "13:00"},
+				{Start: "11:00", End: "15:00"},
+			},
+			now:    at(time.October, 14, 10, 0),
+			active: true,
+			end:    at(time.October, 14, 15, 0),
+		},
+		{
+			name: "full_day",
+			windows: []types.ScheduleWindow{
+				{Days: []string{"mon"}, Start: "00:00", End: "24:00"},
+			},
+			now:    at(time.October, 12, 12, 0),
+			active: true,
+			end:    at(time.October, 13, 0, 0),
+		},
+		{
+			name: "full_days_joined",
+			windows: []types.ScheduleWindow{
+				{Days: []string{"mon", "tue"}, Start: "00:00", End: "24:00"},
+			},
+			now:    at(time.October, 12, 12, 0),
+			active: true,
+			end:    at(time.October, 14, 0, 0),
+		},
+		{
+			name: "utc_time",
+			windows: []types.ScheduleWindow{
+				{Start: "09:00", End: "17:00"},
+			},
+			now:    time.Date(2026, time.October, 14, 16, 0, 0, 0, time.UTC),
+			active: true,
+			end:    at(time.October, 14, 17, 0),
+		},
+		{
+			name: "dst_start_overnight",
+			windows: []types.ScheduleWindow{
+				{Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.March, 7, 23, 0),
+			active: true,
+			end:    at(time.March, 8, 6, 0),
+		},
+		{
+			name: "dst_start_skipped_hour",
+			windows: []types.ScheduleWindow{
+				{Start: "01:00", End: "03:00"},
+			},
+			now:    at(time.March, 8, 1, 30),
+			active: true,
+			end:    at(time.March, 8, 3, 0),
+		},
+		{
+			name: "dst_end_overnight",
+			windows: []types.ScheduleWindow{
+				{Start: "22:00", End: "06:00"},
+			},
+			now:    at(time.October, 31, 23, 0),
+			active: true,
+			end:    at(time.November, 1, 6, 0),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sched := &types.Schedule{
+				Timezone: testTimezone,
+				Windows:  test.windows,
+			}
+
+			active, end := Window(sched, test.now)
+			if active != test.active {
+				t.Fatalf("active %t, expected %t", active, test.active)
+			}
+			if !end.Equal(test.end) {
+				t.Errorf("end %s, expected %s", end, test.end)
+			}
+		})
+	}
+}
+
+func TestWindowDstDuration(t *testing.T) {
+	loc, err := time.LoadLocation(testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sched := &types.Schedule{
+		Timezone: testTimezone,
+		Windows: []types.ScheduleWindow{
+			{Start: "22:00", End: "06:00"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "standard",
+			now:      time.Date(2026, time.October, 14, 23, 0, 0, 0, loc),
+			expected: 7 * time.Hour,
+		},
+		{
+			name:     "dst_start",
+			now:      time.Date(2026, time.March, 7, 23, 0, 0, 0, loc),
+			expected: 6 * time.Hour,
+		},
+		{
+			name:     "dst_end",
+			now:      time.Date(2026, time.October, 31, 23, 0, 0, 0, loc),
+			expected: 8 * time.Hour,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, end := Window(sched, test.now)
+			if end.Sub(test.now) != test.expected {
+				t.Errorf("duration %s, expected %s",
+					end.Sub(test.now), test.expected)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		sched *types.Schedule
+		err   bool
+	}{
+		{
+			name:  "nil",
+			sched: nil,
+			err:   false,
+		},
+		{
+			name: "valid",
+			sched: &types.Schedule{
+				Timezone: testTimezone,
+				Windows: []types.ScheduleWindow{
+					{Days: []string{"Mon", " fri "}, Start: "9:00", End: "24:00"},
+				},
+				Warning: 60,
+			},
+			err: false,
+		},
+		{
+			name: "local_timezone",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Start: "22:00", End: "06:00"},
+				},
+			},
+			err: false,
+		},
+		{
+			name: "invalid_timezone",
+			sched: &types.Schedule{
+				Timezone: "Invalid/Zone",
+			},
+			err: true,
+		},
+		{
+			name: "negative_warning",
+			sched: &types.Schedule{
+				Warning: -1,
+			},
+			err: true,
+		},
+		{
+			name: "invalid_start_hour",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Start: "25:00", End: "06:00"},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "invalid_start_format",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Start: "9", End: "06:00"},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "invalid_end_minute",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Start: "09:00", End: "24:30"},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "invalid_end_format",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Start: "09:00", End: "ab:cd"},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "invalid_day",
+			sched: &types.Schedule{
+				Windows: []types.ScheduleWindow{
+					{Days: []string{"funday"}, Start: "09:00", End: "17:00"},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.sched)
+			if (err != nil) != test.err {
+				t.Errorf("error %v, expected error %t", err, test.err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package sprofile
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"crypto/subtle"
@@ -19,6 +20,9 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-client-electron/service/errortypes"
 	"github.com/pritunl/pritunl-client-electron/service/platform"
+	"github.com/pritunl/pritunl-client-electron/service/proxy"
+	"github.com/pritunl/pritunl-client-electron/service/resolver"
+	"github.com/pritunl/pritunl-client-electron/service/tlspin"
 	"github.com/pritunl/pritunl-client-electron/service/types"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 )
@@ -26,6 +30,8 @@ import (
 var (
 	clientSyncInsecure = &http.Client{
 		Transport: &http.Transport{
+			Proxy:               proxy.Func,
+			DialContext:         resolver.DialContext,
 			TLSHandshakeTimeout: 5 * time.Second,
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
@@ -80,6 +86,14 @@ type Sprofile struct {
 	ServerBoxPublicKey string                      `json:"server_box_public_key"`
 	RegistrationKey    string                      `json:"registration_key"`
 	OvpnData           string                      `json:"ovpn_data"`
+	ReconnectPolicy    *types.ReconnectPolicy      `json:"reconnect_policy"`
+	Proxy              *types.Proxy                `json:"proxy"`
+	TlsPins            []string                    `json:"tls_pins"`
+	Schedule           *types.Schedule             `json:"schedule"`
+	TrustedNetworks    []types.TrustedNetwork      `json:"trusted_networks"`
+	KillSwitch         bool                        `json:"kill_switch"`
+	Ipv6Guard          bool                        `json:"ipv6_guard"`
+	TlsPinsTofu        map[string]string           `json:"tls_pins_tofu"`
 	Path               string                      `json:"-"`
 	Password           string                      `json:"password"`
 	AuthErrorCount     int                         `json:"-"`
@@ -122,6 +136,13 @@ type SprofileClient struct {
 	ServerBoxPublicKey string                      `json:"server_box_public_key"`
 	RegistrationKey    string                      `json:"registration_key"`
 	OvpnData           string                      `json:"ovpn_data"`
+	ReconnectPolicy    *types.ReconnectPolicy      `json:"reconnect_policy"`
+	Proxy              *types.Proxy                `json:"proxy"`
+	TlsPins            []string                    `json:"tls_pins"`
+	Schedule           *types.Schedule             `json:"schedule"`
+	TrustedNetworks    []types.TrustedNetwork      `json:"trusted_networks"`
+	KillSwitch         bool                        `json:"kill_switch"`
+	Ipv6Guard          bool                        `json:"ipv6_guard"`
 }
 
 func (s *Sprofile) BasePath() string {
@@ -167,6 +188,13 @@ func (s *Sprofile) Client() (sprflc *SprofileClient) {
 		ServerBoxPublicKey: s.ServerBoxPublicKey,
 		RegistrationKey:    s.RegistrationKey,
 		OvpnData:           s.OvpnData,
+		ReconnectPolicy:    s.ReconnectPolicy.Copy(),
+		Proxy:              s.Proxy.Redact(),
+		TlsPins:            s.TlsPins,
+		Schedule:           s.Schedule.Copy(),
+		TrustedNetworks:    s.TrustedNetworks,
+		KillSwitch:         s.KillSwitch,
+		Ipv6Guard:          s.Ipv6Guard,
 	}
 
 	return
@@ -189,6 +217,28 @@ func (s *Sprofile) Copy() (sprfl *Sprofile) {
 		}
 	}
 
+	var tlsPins []string
+	if s.TlsPins != nil {
+		tlsPins = []string{}
+		for _, pin := range s.TlsPins {
+			tlsPins = append(tlsPins, pin)
+		}
+	}
+
+	var tlsPinsTofu map[string]string
+	if s.TlsPinsTofu != nil {
+		tlsPinsTofu = map[string]string{}
+		for host, pin := range s.TlsPinsTofu {
+			tlsPinsTofu[host] = pin
+		}
+	}
+
+	var trustedNetworks []types.TrustedNetwork
+	if s.TrustedNetworks != nil {
+		trustedNetworks = append(
+			[]types.TrustedNetwork{}, s.TrustedNetworks...)
+	}
+
 	sprfl = &Sprofile{
 		Id:                 s.Id,
 		Name:               s.Name,
@@ -227,6 +277,14 @@ func (s *Sprofile) Copy() (sprfl *Sprofile) {
 		ServerBoxPublicKey: s.ServerBoxPublicKey,
 		RegistrationKey:    s.RegistrationKey,
 		OvpnData:           s.OvpnData,
+		ReconnectPolicy:    s.ReconnectPolicy.Copy(),
+		Proxy:              s.Proxy.Copy(),
+		TlsPins:            tlsPins,
+		Schedule:           s.Schedule.Copy(),
+		TrustedNetworks:    trustedNetworks,
+		KillSwitch:         s.KillSwitch,
+		Ipv6Guard:          s.Ipv6Guard,
+		TlsPinsTofu:        tlsPinsTofu,
 		Path:               s.Path,
 		Password:           s.Password,
 		AuthErrorCount:     s.AuthErrorCount,
@@ -406,6 +464,7 @@ func (s *Sprofile) syncUpdate(data string) (updated bool, err error) {
 		s.SyncHash = confData.SyncHash
 		s.ServerPublicKey = confData.ServerPublicKey
 		s.ServerBoxPublicKey = confData.ServerBoxPublicKey
+		s.TlsPins = confData.TlsPins
 	}
 
 	if strings.Contains(s.OvpnData, "key-direction") &&
@@ -501,7 +560,8 @@ func (s *Sprofile) syncProfile(host string) (updated bool, err error) {
 	rawSignature := hashFunc.Sum(nil)
 	sig := base64.StdEncoding.EncodeToString(rawSignature)
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		proxy.WithProfile(context.Background(), s.Proxy),
 		"GET",
 		u,
 		nil,
@@ -519,7 +579,14 @@ func (s *Sprofile) syncProfile(host string) (updated bool, err error) {
 	req.Header.Set("Auth-Signature", sig)
 	req.Header.Set("User-Agent", "pritunl")
 
-	res, err := clientSyncInsecure.Do(req)
+	tofuPin := ""
+	client := clientSyncInsecure
+	verify := s.verifyPeer(host, &tofuPin)
+	if verify != nil {
+		client = tlspin.Client(clientSyncInsecure, verify)
+	}
+
+	res, err := client.Do(req)
 	if err != nil {
 		err = &errortypes.RequestError{
 			errors.Wrap(err, "sprofile: Sync profile connection error"),
@@ -564,6 +631,8 @@ func (s *Sprofile) syncProfile(host string) (updated bool, err error) {
 		return
 	}
 
+	s.storeTlsPin(host, tofuPin)
+
 	updated, err = s.syncUpdate(syncData.Conf)
 	if err != nil {
 		return
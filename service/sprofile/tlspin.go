@@ -0,0 +1,131 @@
+package sprofile
+
+import (
+	"net/url"
+
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/tlspin"
+	"github.com/sirupsen/logrus"
+)
+
+type TlsPinEvent struct {
+	Id   string `json:"id"`
+	Host string `json:"host"`
+}
+
+// GetTlsPins returns the configured pins, when no pins are configured and
+// trust on first use is enabled the stored pin for the host is returned
+// or tofu is set if the host has not been seen
+func (s *Sprofile) GetTlsPins(host string) (pins []string, tofu bool) {
+	if !tlspin.IsEmpty(s.TlsPins) {
+		pins = s.TlsPins
+		return
+	}
+
+	if !config.Config.TlsPinTofu {
+		return
+	}
+
+	pin := s.TlsPinsTofu[host]
+	if pin != "" {
+		pins = []string{pin}
+	} else {
+		tofu = true
+	}
+
+	return
+}
+
+// SetTlsPinTofu stores the pin trusted on first use for the host, the
+// cached profile is replaced with a copy to avoid modifying a profile that
+// is in use
+func SetTlsPinTofu(prflId, host, pin string) (err error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	prflsCache := []*Sprofile{}
+
+	for _, prfl := range cache {
+		if prfl.Id == prflId {
+			prfl = prfl.Copy()
+			if prfl.TlsPinsTofu == nil {
+				prfl.TlsPinsTofu = map[string]string{}
+			}
+			prfl.TlsPinsTofu[host] = pin
+
+			err = prfl.Commit()
+			if err != nil {
+				return
+			}
+		}
+		prflsCache = append(prflsCache, prfl)
+	}
+
+	cache = prflsCache
+
+	return
+}
+
+// storeTlsPin saves the first use pin of the sync host once the synced
+// configuration signature has been checked
+func (s *Sprofile) storeTlsPin(syncHost, pin string) {
+	if pin == "" {
+		return
+	}
+
+	u, err := url.Parse(syncHost)
+	if err != nil {
+		return
+	}
+	host := u.Hostname()
+
+	logrus.WithFields(logrus.Fields{
+		"profile_id": s.Id,
+		"host":       host,
+		"pin":        pin,
+	}).Info("sprofile: Storing trusted TLS pin")
+
+	err = SetTlsPinTofu(s.Id, host, pin)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": s.Id,
+			"host":       host,
+			"error":      err,
+		}).Error("sprofile: Failed to store trusted TLS pin")
+	}
+}
+
+// verifyPeer returns the peer verify function for the sync host, a
+// mismatch sends an event with the profile and host
+func (s *Sprofile) verifyPeer(syncHost string,
+	tofuPin *string) tlspin.VerifyFunc {
+
+	u, err := url.Parse(syncHost)
+	if err != nil {
+		return nil
+	}
+	host := u.Hostname()
+
+	pins, tofu := s.GetTlsPins(host)
+	if !tofu {
+		tofuPin = nil
+	}
+
+	return tlspin.VerifyPeer(pins, tofuPin, func(err error) {
+		logrus.WithFields(logrus.Fields{
+			"profile_id": s.Id,
+			"host":       host,
+			"error":      err,
+		}).Error("sprofile: Sync TLS certificate pin mismatch")
+
+		evt := &event.Event{
+			Type: "tls_pin_mismatch",
+			Data: &TlsPinEvent{
+				Id:   s.Id,
+				Host: host,
+			},
+		}
+		evt.Init()
+	})
+}
@@ -67,6 +67,25 @@ func Deactivate(prflId string) {
 	cache = prflsCache
 }
 
+// SetState sets the profile state without an interactive connection, the
+// state is not committed
+func SetState(prflId string, state bool) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	prflsCache := []*Sprofile{}
+
+	for _, prfl := range cache {
+		if prfl.Id == prflId {
+			prfl.State = state
+			prfl.Interactive = false
+		}
+		prflsCache = append(prflsCache, prfl)
+	}
+
+	cache = prflsCache
+}
+
 func SetAuthErrorCount(prflId string, errorCount int) {
 	cacheLock.Lock()
 	defer cacheLock.Unlock()
@@ -0,0 +1,155 @@
+package tlspin
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+)
+
+const (
+	SpkiPrefix = "sha256/"
+	CertPrefix = "cert-sha256/"
+)
+
+type VerifyFunc func(rawCerts [][]byte,
+	verifiedChains [][]*x509.Certificate) error
+
+// Spki returns the pin of the certificate subject public key info
+func Spki(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return SpkiPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Cert returns the pin of the full certificate
+func Cert(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return CertPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Normalize adds the default SPKI prefix to pins without a prefix
+func Normalize(pin string) string {
+	pin = strings.TrimSpace(pin)
+	if pin == "" {
+		return ""
+	}
+
+	if !strings.HasPrefix(pin, SpkiPrefix) &&
+		!strings.HasPrefix(pin, CertPrefix) {
+
+		pin = SpkiPrefix + pin
+	}
+
+	return pin
+}
+
+// Match returns true when the leaf certificate matches any pin, the chain
+// is not verified so other certificates in the chain are not trusted
+func Match(pins []string, cert *x509.Certificate) bool {
+	for _, pin := range pins {
+		pin = Normalize(pin)
+		if pin == "" {
+			continue
+		}
+
+		if strings.HasPrefix(pin, CertPrefix) {
+			if pin == Cert(cert) {
+				return true
+			}
+		} else if pin == Spki(cert) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsEmpty(pins []string) bool {
+	for _, pin := range pins {
+		if Normalize(pin) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ParseCerts(rawCerts [][]byte) (certs []*x509.Certificate, err error) {
+	certs = []*x509.Certificate{}
+
+	for _, rawCert := range rawCerts {
+		cert, e := x509.ParseCertificate(rawCert)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "tlspin: Failed to parse peer certificate"),
+			}
+			return
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		err = &errortypes.VerificationError{
+			errors.New("tlspin: Peer did not provide a certificate"),
+		}
+		return
+	}
+
+	return
+}
+
+// VerifyPeer returns the peer verify function for the pins or nil if no
+// verification is needed. A non nil tofuPin trusts the peer on first use
+// and receives the leaf pin, otherwise onMismatch is called with the error
+// when the leaf does not match the pins. Invalid certificates fail without
+// calling onMismatch.
+func VerifyPeer(pins []string, tofuPin *string,
+	onMismatch func(err error)) VerifyFunc {
+
+	if tofuPin == nil && IsEmpty(pins) {
+		return nil
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) (err error) {
+		certs, err := ParseCerts(rawCerts)
+		if err != nil {
+			return
+		}
+
+		if tofuPin != nil {
+			*tofuPin = Spki(certs[0])
+			return
+		}
+
+		if !Match(pins, certs[0]) {
+			err = &errortypes.VerificationError{
+				errors.Newf("tlspin: Peer certificate pin mismatch %s",
+					Spki(certs[0])),
+			}
+			if onMismatch != nil {
+				onMismatch(err)
+			}
+			return
+		}
+
+		return
+	}
+}
+
+// Client returns a copy of the client with the verify function set on the
+// transport TLS configuration
+func Client(base *http.Client, verify VerifyFunc) (client *http.Client) {
+	transport := base.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.VerifyPeerCertificate = verify
+
+	client = &http.Client{
+		Transport: transport,
+		Timeout:   base.Timeout,
+	}
+
+	return
+}
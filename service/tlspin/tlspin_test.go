@@ -0,0 +1,281 @@
+package tlspin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCert(t *testing.T) (cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "test",
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+
+	raw, err := x509.CreateCertificate(
+		rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err = x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		pin      string
+		expected string
+	}{
+		{
+			pin:      "",
+			expected: "",
+		},
+		{
+			pin:      "  ",
+			expected: "",
+		},
+		{
+			pin:      "abc=",
+			expected: "sha256/abc=",
+		},
+		{
+			pin:      " sha256/abc= ",
+			expected: "sha256/abc=",
+		},
+		{
+			pin:      "cert-sha256/abc=",
+			expected: "cert-sha256/abc=",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pin, func(t *testing.T) {
+			pin := Normalize(test.pin)
+			if pin != test.expected {
+				t.Errorf("pin %q, expected %q", pin, test.expected)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cert := testCert(t)
+	other := testCert(t)
+
+	tests := []struct {
+		name     string
+		pins     []string
+		expected bool
+	}{
+		{
+			name:     "empty",
+			pins:     []string{},
+			expected: false,
+		},
+		{
+			name:     "spki",
+			pins:     []string{Spki(cert)},
+			expected: true,
+		},
+		{
+			name:     "spki_no_prefix",
+			pins:     []string{strings.TrimPrefix(Spki(cert), SpkiPrefix)},
+			expected: true,
+		},
+		{
+			name:     "cert",
+			pins:     []string{Cert(cert)},
+			expected: true,
+		},
+		{
+			name:     "backup_pin",
+			pins:     []string{Spki(other), Spki(cert)},
+			expected: true,
+		},
+		{
+			name:     "other_spki",
+			pins:     []string{Spki(other)},
+			expected: false,
+		},
+		{
+			name:     "other_cert",
+			pins:     []string{Cert(other)},
+			expected: false,
+		},
+		{
+			name: "cert_hash_as_spki",
+			pins: []string{
+				SpkiPrefix + strings.TrimPrefix(Cert(cert), CertPrefix),
+			},
+			expected: false,
+		},
+		{
+			name:     "blank",
+			pins:     []string{"", " "},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match := Match(test.pins, cert)
+			if match != test.expected {
+				t.Errorf("match %t, expected %t", match, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	tests := []struct {
+		name     string
+		pins     []string
+		expected bool
+	}{
+		{
+			name:     "nil",
+			pins:     nil,
+			expected: true,
+		},
+		{
+			name:     "blank",
+			pins:     []string{"", "  "},
+			expected: true,
+		},
+		{
+			name:     "pin",
+			pins:     []string{"", "abc="},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if IsEmpty(test.pins) != test.expected {
+				t.Errorf("empty %t, expected %t",
+					!test.expected, test.expected)
+			}
+		})
+	}
+}
+
+func TestVerifyPeer(t *testing.T) {
+	cert := testCert(t)
+	other := testCert(t)
+
+	tests := []struct {
+		name     string
+		pins     []string
+		tofu     bool
+		certs    [][]byte
+		noVerify bool
+		err      bool
+		mismatch bool
+		tofuPin  string
+	}{
+		{
+			name:     "no_pins",
+			pins:     []string{},
+			noVerify: true,
+		},
+		{
+			name:  "match",
+			pins:  []string{Spki(cert)},
+			certs: [][]byte{cert.Raw, other.Raw},
+		},
+		{
+			name:     "mismatch",
+			pins:     []string{Spki(other)},
+			certs:    [][]byte{cert.Raw},
+			err:      true,
+			mismatch: true,
+		},
+		{
+			name:     "intermediate_pin",
+			pins:     []string{Spki(other)},
+			certs:    [][]byte{cert.Raw, other.Raw},
+			err:      true,
+			mismatch: true,
+		},
+		{
+			name:  "no_certs",
+			pins:  []string{Spki(cert)},
+			certs: [][]byte{},
+			err:   true,
+		},
+		{
+			name:  "invalid_cert",
+			pins:  []string{Spki(cert)},
+			certs: [][]byte{[]byte("invalid")},
+			err:   true,
+		},
+		{
+			name:    "tofu",
+			pins:    []string{},
+			tofu:    true,
+			certs:   [][]byte{cert.Raw},
+			tofuPin: Spki(cert),
+		},
+		{
+			name:  "tofu_no_certs",
+			pins:  []string{},
+			tofu:  true,
+			certs: [][]byte{},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tofuPin *string
+			if test.tofu {
+				tofuPin = new(string)
+			}
+			mismatch := false
+
+			verify := VerifyPeer(test.pins, tofuPin, func(err error) {
+				mismatch = true
+			})
+			if test.noVerify {
+				if verify != nil {
+					t.Errorf("verify set, expected nil")
+				}
+				return
+			}
+			if verify == nil {
+				t.Fatalf("verify nil")
+			}
+
+			err := verify(test.certs, nil)
+			if (err != nil) != test.err {
+				t.Errorf("error %v, expected error %t", err, test.err)
+			}
+			if mismatch != test.mismatch {
+				t.Errorf("mismatch %t, expected %t", mismatch, test.mismatch)
+			}
+			if tofuPin != nil && *tofuPin != test.tofuPin {
+				t.Errorf("tofu pin %q, expected %q", *tofuPin, test.tofuPin)
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package trusted
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"github.com/pritunl/pritunl-client-electron/service/tlspin"
+	"github.com/pritunl/pritunl-client-electron/service/types"
+	"github.com/sirupsen/logrus"
+)
+
+const HostTimeout = 3 * time.Second
+
+var (
+	states     = map[string]bool{}
+	statesLock = sync.Mutex{}
+)
+
+// Exclude contains the interfaces, addresses and search domains of the
+// active connections which are ignored when detecting trusted networks
+type Exclude struct {
+	Ifaces  []string
+	Addrs   []string
+	Domains []string
+}
+
+type State struct {
+	GatewayMacs   []string
+	SearchDomains []string
+	Addrs         []net.IP
+	ifaces        []string
+	excludeAddrs  []net.IP
+	hosts         map[string]bool
+}
+
+func normalizeMac(mac string) string {
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	mac = strings.ReplaceAll(mac, "-", ":")
+
+	parts := strings.Split(mac, ":")
+	if len(parts) != 6 {
+		return mac
+	}
+
+	for i, part := range parts {
+		if len(part) == 1 {
+			parts[i] = "0" + part
+		}
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func normalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func parseAddr(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if strings.Contains(addr, "/") {
+		ip, _, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil
+		}
+		return ip
+	}
+	return net.ParseIP(addr)
+}
+
+// GetState reads the current network state
+func GetState(exclude *Exclude) (state *State) {
+	state = &State{
+		GatewayMacs:   []string{},
+		SearchDomains: []string{},
+		Addrs:         []net.IP{},
+		ifaces:        []string{},
+		excludeAddrs:  []net.IP{},
+		hosts:         map[string]bool{},
+	}
+
+	excludeIfaces := set.NewSet()
+	for _, iface := range exclude.Ifaces {
+		if iface != "" {
+			excludeIfaces.Add(iface)
+		}
+	}
+	for _, addr := range exclude.Addrs {
+		ip := parseAddr(addr)
+		if ip != nil {
+			state.excludeAddrs = append(state.excludeAddrs, ip)
+		}
+	}
+
+	localAddrs := []net.IP{}
+	localIfaces := map[string]string{}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("trusted: Failed to read interfaces")
+	} else {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 ||
+				iface.Flags&net.FlagLoopback != 0 {
+
+				continue
+			}
+
+			addrs, e := iface.Addrs()
+			if e != nil {
+				continue
+			}
+
+			excluded := excludeIfaces.Contains(iface.Name)
+			for _, addr := range addrs {
+				ip := parseAddr(addr.String())
+				if ip == nil {
+					continue
+				}
+
+				if excluded {
+					state.excludeAddrs = append(state.excludeAddrs, ip)
+				} else {
+					localAddrs = append(localAddrs, ip)
+					localIfaces[ip.String()] = iface.Name
+				}
+			}
+		}
+	}
+
+	ifaceNames := set.NewSet()
+	for _, ip := range localAddrs {
+		if !state.isExcluded(ip) {
+			state.Addrs = append(state.Addrs, ip)
+
+			name := localIfaces[ip.String()]
+			if !ifaceNames.Contains(name) {
+				ifaceNames.Add(name)
+				state.ifaces = append(state.ifaces, name)
+			}
+		}
+	}
+
+	macs, err := getGatewayMacs()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("trusted: Failed to read gateway hardware address")
+	} else {
+		for _, mac := range macs {
+			mac = normalizeMac(mac)
+			if mac != "" {
+				state.GatewayMacs = append(state.GatewayMacs, mac)
+			}
+		}
+	}
+
+	excludeDomains := set.NewSet()
+	for _, domain := range exclude.Domains {
+		excludeDomains.Add(normalizeDomain(domain))
+	}
+
+	domains, err := getSearchDomains()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("trusted: Failed to read search domains")
+	} else {
+		for _, domain := range domains {
+			domain = normalizeDomain(domain)
+			if domain != "" && !excludeDomains.Contains(domain) {
+				state.SearchDomains = append(state.SearchDomains, domain)
+			}
+		}
+	}
+
+	return
+}
+
+func (s *State) isExcluded(ip net.IP) bool {
+	for _, excludeIp := range s.excludeAddrs {
+		if excludeIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns a string that changes when the network state changes
+func (s *State) Hash() string {
+	vals := []string{}
+
+	for _, mac := range s.GatewayMacs {
+		vals = append(vals, "mac:"+mac)
+	}
+	for _, domain := range s.SearchDomains {
+		vals = append(vals, "domain:"+domain)
+	}
+	for _, addr := range s.Addrs {
+		vals = append(vals, "addr:"+addr.String())
+	}
+
+	sort.Strings(vals)
+
+	return strings.Join(vals, ",")
+}
+
+// dialHost connects to the host over the interfaces that are not used by
+// a connection, the socket is bound to the interface so the connection
+// does not use the routes of a tunnel
+func (s *State) dialHost(host string) (conn net.Conn, err error) {
+	for _, iface := range s.ifaces {
+		dialer := &net.Dialer{
+			Timeout: HostTimeout,
+			Control: bindIface(iface),
+		}
+
+		conn, err = dialer.Dial("tcp", host)
+		if err == nil {
+			return
+		}
+	}
+
+	if err == nil {
+		err = &errortypes.RequestError{
+			errors.New("trusted: No interface available for trusted host"),
+		}
+	}
+
+	return
+}
+
+// checkHost connects to the host outside of the tunnel and verifies the
+// certificate pin, only the leaf certificate is matched since the chain is
+// not verified
+func (s *State) checkHost(host, pin string) bool {
+	key := host + "|" + pin
+	if result, ok := s.hosts[key]; ok {
+		return result
+	}
+
+	result := false
+	defer func() {
+		s.hosts[key] = result
+	}()
+
+	if tlspin.IsEmpty([]string{pin}) {
+		return false
+	}
+
+	conn, err := s.dialHost(host)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"host":  host,
+			"error": err,
+		}).Info("trusted: Trusted host unreachable")
+		return false
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if ok && s.isExcluded(localAddr.IP) {
+		return false
+	}
+
+	serverName, _, _ := net.SplitHostPort(host)
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+		VerifyPeerCertificate: func(rawCerts [][]byte,
+			_ [][]*x509.Certificate) error {
+
+			certs, err := tlspin.ParseCerts(rawCerts)
+			if err != nil {
+				return err
+			}
+
+			if !tlspin.Match([]string{pin}, certs[0]) {
+				return &errortypes.VerificationError{
+					errors.New("trusted: Trusted host pin mismatch"),
+				}
+			}
+
+			return nil
+		},
+	})
+
+	_ = tlsConn.SetDeadline(time.Now().Add(HostTimeout))
+
+	err = tlsConn.Handshake()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"host":  host,
+			"error": err,
+		}).Warn("trusted: Trusted host verification failed")
+		return false
+	}
+
+	result = true
+	return result
+}
+
+// Match returns true when every set field of the rule matches, rules
+// without a pinned host never match since the other fields can be spoofed
+// by any network
+func (s *State) Match(rule types.TrustedNetwork) bool {
+	if rule.Host == "" || tlspin.IsEmpty([]string{rule.HostPin}) {
+		return false
+	}
+
+	if rule.GatewayMac != "" {
+		mac := normalizeMac(rule.GatewayMac)
+		found := false
+		for _, gatewayMac := range s.GatewayMacs {
+			if gatewayMac == mac {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if rule.SearchDomain != "" {
+		domain := normalizeDomain(rule.SearchDomain)
+		found := false
+		for _, searchDomain := range s.SearchDomains {
+			if searchDomain == domain {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if rule.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(rule.Subnet))
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"subnet": rule.Subnet,
+				"error":  err,
+			}).Error("trusted: Invalid trusted network subnet")
+			return false
+		}
+
+		found := false
+		for _, addr := range s.Addrs {
+			if subnet.Contains(addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !s.checkHost(rule.Host, rule.HostPin) {
+		return false
+	}
+
+	return true
+}
+
+// GetHosts returns the hostnames of the trusted hosts in the rules
+func GetHosts(rules []types.TrustedNetwork) (hosts []string) {
+	hosts = []string{}
+
+	for _, rule := range rules {
+		host, _, err := net.SplitHostPort(rule.Host)
+		if err != nil || host == "" {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+
+	return
+}
+
+// Validate checks that each rule has a pinned host and valid fields
+func Validate(rules []types.TrustedNetwork) (err error) {
+	for _, rule := range rules {
+		if rule.Host == "" || tlspin.IsEmpty([]string{rule.HostPin}) {
+			err = &errortypes.ParseError{
+				errors.New("trusted: Trusted network requires host and pin"),
+			}
+			return
+		}
+
+		_, _, e := net.SplitHostPort(rule.Host)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrapf(e, "trusted: Invalid trusted host '%s'",
+					rule.Host),
+			}
+			return
+		}
+
+		if rule.Subnet != "" {
+			_, _, e = net.ParseCIDR(strings.TrimSpace(rule.Subnet))
+			if e != nil {
+				err = &errortypes.ParseError{
+					errors.Wrapf(e, "trusted: Invalid trusted subnet '%s'",
+						rule.Subnet),
+				}
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func (s *State) IsTrusted(rules []types.TrustedNetwork) bool {
+	for _, rule := range rules {
+		if s.Match(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTrusted stores the trusted state of the profile and returns true if
+// the state changed, the first state of a profile is only recorded so the
+// profile state set by the user is kept until the network changes
+func SetTrusted(prflId string, trusted bool) (changed bool) {
+	statesLock.Lock()
+	defer statesLock.Unlock()
+
+	cur, ok := states[prflId]
+	states[prflId] = trusted
+	if ok && cur != trusted {
+		changed = true
+	}
+
+	return
+}
+
+func IsTrusted(prflId string) bool {
+	statesLock.Lock()
+	defer statesLock.Unlock()
+
+	return states[prflId]
+}
+
+func Clear(prflId string) {
+	statesLock.Lock()
+	delete(states, prflId)
+	statesLock.Unlock()
+}
@@ -0,0 +1,103 @@
+package trusted
+
+import (
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"golang.org/x/sys/unix"
+)
+
+func getGatewayMacs() (macs []string, err error) {
+	macs = []string{}
+
+	output, err := utils.ExecOutput("/sbin/route", "-n", "get", "default")
+	if err != nil {
+		return
+	}
+
+	gateway := ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "gateway:" {
+			gateway = fields[1]
+			break
+		}
+	}
+
+	if net.ParseIP(gateway) == nil {
+		return
+	}
+
+	output, err = utils.ExecOutput("/usr/sbin/arp", "-n", gateway)
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(output)
+	for i, field := range fields {
+		if field == "at" && i+1 < len(fields) {
+			if _, e := net.ParseMAC(
+				normalizeMac(fields[i+1])); e == nil {
+
+				macs = append(macs, fields[i+1])
+			}
+			break
+		}
+	}
+
+	return
+}
+
+func getSearchDomains() (domains []string, err error) {
+	domains = []string{}
+
+	output, err := utils.ExecOutput("/usr/sbin/scutil", "--dns")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		lineSpl := strings.SplitN(line, ":", 2)
+		if len(lineSpl) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(lineSpl[0])
+		if strings.HasPrefix(key, "search domain[") ||
+			key == "domain" {
+
+			domains = append(domains, strings.TrimSpace(lineSpl[1]))
+		}
+	}
+
+	return
+}
+
+func bindIface(iface string) func(network, address string,
+	conn syscall.RawConn) error {
+
+	return func(network, address string, conn syscall.RawConn) (err error) {
+		ifc, err := net.InterfaceByName(iface)
+		if err != nil {
+			return
+		}
+
+		var bindErr error
+		err = conn.Control(func(fd uintptr) {
+			if strings.HasSuffix(network, "6") {
+				bindErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6,
+					unix.IPV6_BOUND_IF, ifc.Index)
+			} else {
+				bindErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP,
+					unix.IP_BOUND_IF, ifc.Index)
+			}
+		})
+		if err == nil {
+			err = bindErr
+		}
+
+		return
+	}
+}
@@ -0,0 +1,138 @@
+package trusted
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-client-electron/service/errortypes"
+	"golang.org/x/sys/unix"
+)
+
+const rtfGateway = 0x2
+
+func getGateways() (gateways []net.IP, err error) {
+	gateways = []net.IP{}
+
+	data, err := ioutil.ReadFile("/proc/net/route")
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "trusted: Failed to read routes"),
+		}
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || fields[1] != "00000000" ||
+			fields[7] != "00000000" {
+
+			continue
+		}
+
+		flags, e := strconv.ParseInt(fields[3], 16, 64)
+		if e != nil || flags&rtfGateway == 0 {
+			continue
+		}
+
+		gatewayHex, e := hex.DecodeString(fields[2])
+		if e != nil || len(gatewayHex) != 4 {
+			continue
+		}
+
+		gateway := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(gateway,
+			binary.BigEndian.Uint32(gatewayHex))
+		gateways = append(gateways, gateway)
+	}
+
+	return
+}
+
+func getGatewayMacs() (macs []string, err error) {
+	macs = []string{}
+
+	gateways, err := getGateways()
+	if err != nil {
+		return
+	}
+
+	if len(gateways) == 0 {
+		return
+	}
+
+	data, err := ioutil.ReadFile("/proc/net/arp")
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "trusted: Failed to read neighbors"),
+		}
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+
+		for _, gateway := range gateways {
+			if gateway.Equal(ip) && fields[3] != "00:00:00:00:00:00" {
+				macs = append(macs, fields[3])
+			}
+		}
+	}
+
+	return
+}
+
+func getSearchDomains() (domains []string, err error) {
+	domains = []string{}
+
+	data, err := ioutil.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "trusted: Failed to read resolv.conf"),
+		}
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		if fields[0] == "search" || fields[0] == "domain" {
+			domains = append(domains, fields[1:]...)
+		}
+	}
+
+	return
+}
+
+func bindIface(iface string) func(network, address string,
+	conn syscall.RawConn) error {
+
+	return func(network, address string, conn syscall.RawConn) (err error) {
+		var bindErr error
+		err = conn.Control(func(fd uintptr) {
+			bindErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET,
+				unix.SO_BINDTODEVICE, iface)
+		})
+		if err == nil {
+			err = bindErr
+		}
+
+		return
+	}
+}
@@ -0,0 +1,387 @@
+package trusted
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pritunl/pritunl-client-electron/service/types"
+)
+
+const (
+	testHost = "trusted.example.com:443"
+	testPin  = "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+)
+
+// testState returns a state with the host check results cached so the
+// rules are matched without connecting to the host
+func testState(hostTrusted bool) *State {
+	return &State{
+		GatewayMacs:   []string{"00:11:22:33:44:55"},
+		SearchDomains: []string{"corp.example.com"},
+		Addrs: []net.IP{
+			net.ParseIP("192.168.10.20"),
+			net.ParseIP("fd00::20"),
+		},
+		hosts: map[string]bool{
+			testHost + "|" + testPin: hostTrusted,
+		},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        types.TrustedNetwork
+		hostTrusted bool
+		expected    bool
+	}{
+		{
+			name: "host_only",
+			rule: types.TrustedNetwork{
+				Host:    testHost,
+				HostPin: testPin,
+			},
+			hostTrusted: true,
+			expected:    true,
+		},
+		{
+			name: "host_untrusted",
+			rule: types.TrustedNetwork{
+				Host:    testHost,
+				HostPin: testPin,
+			},
+			hostTrusted: false,
+			expected:    false,
+		},
+		{
+			name: "no_host",
+			rule: types.TrustedNetwork{
+				GatewayMac:   "00:11:22:33:44:55",
+				SearchDomain: "corp.example.com",
+				Subnet:       "192.168.10.0/24",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+		{
+			name: "no_pin",
+			rule: types.TrustedNetwork{
+				Host:       testHost,
+				GatewayMac: "00:11:22:33:44:55",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+		{
+			name: "all_fields",
+			rule: types.TrustedNetwork{
+				Host:         testHost,
+				HostPin:      testPin,
+				GatewayMac:   "00-11-22-33-44-55",
+				SearchDomain: "Corp.Example.com.",
+				Subnet:       "192.168.10.0/24",
+			},
+			hostTrusted: true,
+			expected:    true,
+		},
+		{
+			name: "all_fields_host_untrusted",
+			rule: types.TrustedNetwork{
+				Host:         testHost,
+				HostPin:      testPin,
+				GatewayMac:   "00:11:22:33:44:55",
+				SearchDomain: "corp.example.com",
+				Subnet:       "192.168.10.0/24",
+			},
+			hostTrusted: false,
+			expected:    false,
+		},
+		{
+			name: "short_mac",
+			rule: types.TrustedNetwork{
+				Host:       testHost,
+				HostPin:    testPin,
+				GatewayMac: "0:11:22:33:44:55",
+			},
+			hostTrusted: true,
+			expected:    true,
+		},
+		{
+			name: "other_mac",
+			rule: types.TrustedNetwork{
+				Host:       testHost,
+				HostPin:    testPin,
+				GatewayMac: "00:11:22:33:44:66",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+		{
+			name: "other_domain",
+			rule: types.TrustedNetwork{
+				Host:         testHost,
+				HostPin:      testPin,
+				SearchDomain: "example.com",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+		{
+			name: "ipv6_subnet",
+			rule: types.TrustedNetwork{
+				Host:    testHost,
+				HostPin: testPin,
+				Subnet:  "fd00::/64",
+			},
+			hostTrusted: true,
+			expected:    true,
+		},
+		{
+			name: "other_subnet",
+			rule: types.TrustedNetwork{
+				Host:    testHost,
+				HostPin: testPin,
+				Subnet:  "10.0.0.0/8",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+		{
+			name: "invalid_subnet",
+			rule: types.TrustedNetwork{
+				Host:    testHost,
+				HostPin: testPin,
+				Subnet:  "192.168.10.0",
+			},
+			hostTrusted: true,
+			expected:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := testState(test.hostTrusted)
+			match := state.Match(test.rule)
+			if match != test.expected {
+				t.Errorf("match %t, expected %t", match, test.expected)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []types.TrustedNetwork
+		err   bool
+	}{
+		{
+			name:  "empty",
+			rules: []types.TrustedNetwork{},
+			err:   false,
+		},
+		{
+			name: "valid",
+			rules: []types.TrustedNetwork{
+				{
+					Host:    testHost,
+					HostPin: testPin,
+					Subnet:  "192.168.10.0/24",
+				},
+			},
+			err: false,
+		},
+		{
+			name: "valid_ipv6_host",
+			rules: []types.TrustedNetwork{
+				{
+					Host:    "[fd00::1]:8443",
+					HostPin: testPin,
+				},
+			},
+			err: false,
+		},
+		{
+			name: "missing_host",
+			rules: []types.TrustedNetwork{
+				{
+					HostPin: testPin,
+				},
+			},
+			err: true,
+		},
+		{
+			name: "missing_pin",
+			rules: []types.TrustedNetwork{
+				{
+					Host: testHost,
+				},
+			},
+			err: true,
+		},
+		{
+			name: "missing_port",
+			rules: []types.TrustedNetwork{
+				{
+					Host:    "trusted.example.com",
+					HostPin: testPin,
+				},
+			},
+			err: true,
+		},
+		{
+			name: "invalid_subnet",
+			rules: []types.TrustedNetwork{
+				{
+					Host:    testHost,
+					HostPin: testPin,
+					Subnet:  "192.168.10.0",
+				},
+			},
+			err: true,
+		},
+		{
+			name: "second_invalid",
+			rules: []types.TrustedNetwork{
+				{
+					Host:    testHost,
+					HostPin: testPin,
+				},
+				{
+					Subnet: "192.168.10.0/24",
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.rules)
+			if (err != nil) != test.err {
+				t.Errorf("error %v, expected error %t", err, test.err)
+			}
+		})
+	}
+}
+
+func TestNormalizeMac(t *testing.T) {
+	tests := []struct {
+		mac      string
+		expected string
+	}{
+		{
+			mac:      "00:11:22:33:44:55",
+			expected: "00:11:22:33:44:55",
+		},
+		{
+			mac:      " 00-1A-22-33-44-5B ",
+			expected: "00:1a:22:33:44:5b",
+		},
+		{
+			mac:      "0:1:2:3:4:5",
+			expected: "00:01:02:03:04:05",
+		},
+		{
+			mac:      "invalid",
+			expected: "invalid",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mac, func(t *testing.T) {
+			mac := normalizeMac(test.mac)
+			if mac != test.expected {
+				t.Errorf("mac %q, expected %q", mac, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetHosts(t *testing.T) {
+	hosts := GetHosts([]types.TrustedNetwork{
+		{
+			Host: testHost,
+		},
+		{
+			Host: "[fd00::1]:8443",
+		},
+		{
+			Host: "invalid",
+		},
+		{
+			Host: "",
+		},
+	})
+
+	expected := []string{"trusted.example.com", "fd00::1"}
+	if len(hosts) != len(expected) {
+		t.Fatalf("hosts %v, expected %v", hosts, expected)
+	}
+	for i := range expected {
+		if hosts[i] != expected[i] {
+			t.Errorf("hosts %v, expected %v", hosts, expected)
+		}
+	}
+}
+
+func TestSetTrusted(t *testing.T) {
+	prflId := "test-profile"
+	defer Clear(prflId)
+
+	steps := []struct {
+		trusted bool
+		changed bool
+	}{
+		{
+			trusted: true,
+			changed: false,
+		},
+		{
+			trusted: true,
+			changed: false,
+		},
+		{
+			trusted: false,
+			changed: true,
+		},
+		{
+			trusted: true,
+			changed: true,
+		},
+	}
+
+	for i, step := range steps {
+		changed := SetTrusted(prflId, step.trusted)
+		if changed != step.changed {
+			t.Errorf("step %d changed %t, expected %t",
+				i, changed, step.changed)
+		}
+		if IsTrusted(prflId) != step.trusted {
+			t.Errorf("step %d trusted %t, expected %t",
+				i, !step.trusted, step.trusted)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	state := testState(true)
+	reordered := &State{
+		GatewayMacs:   state.GatewayMacs,
+		SearchDomains: state.SearchDomains,
+		Addrs: []net.IP{
+			state.Addrs[1],
+			state.Addrs[0],
+		},
+	}
+
+	if state.Hash() != reordered.Hash() {
+		t.Errorf("hash changed with address order")
+	}
+
+	changed := testState(true)
+	changed.GatewayMacs = []string{"00:11:22:33:44:66"}
+	if state.Hash() == changed.Hash() {
+		t.Errorf("hash unchanged with gateway")
+	}
+}
@@ -0,0 +1,90 @@
+package trusted
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"golang.org/x/sys/windows"
+)
+
+func powershell(script string) (lines []string, err error) {
+	lines = []string{}
+
+	output, err := utils.ExecOutput("powershell.exe", "-NoProfile",
+		"-NonInteractive", "-Command", script)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return
+}
+
+func getGatewayMacs() (macs []string, err error) {
+	macs, err = powershell("Get-NetRoute -DestinationPrefix 0.0.0.0/0 " +
+		"-ErrorAction SilentlyContinue | ForEach-Object { " +
+		"Get-NetNeighbor -IPAddress $_.NextHop -InterfaceIndex " +
+		"$_.ifIndex -ErrorAction SilentlyContinue } | " +
+		"Select-Object -ExpandProperty LinkLayerAddress")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func getSearchDomains() (domains []string, err error) {
+	domains, err = powershell("(Get-DnsClientGlobalSetting)." +
+		"SuffixSearchList; Get-DnsClient | Select-Object " +
+		"-ExpandProperty ConnectionSpecificSuffix")
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+const (
+	ipUnicastIf   = 31
+	ipv6UnicastIf = 31
+)
+
+func bindIface(iface string) func(network, address string,
+	conn syscall.RawConn) error {
+
+	return func(network, address string, conn syscall.RawConn) (err error) {
+		ifc, err := net.InterfaceByName(iface)
+		if err != nil {
+			return
+		}
+
+		var bindErr error
+		err = conn.Control(func(fd uintptr) {
+			if strings.HasSuffix(network, "6") {
+				bindErr = windows.SetsockoptInt(windows.Handle(fd),
+					windows.IPPROTO_IPV6, ipv6UnicastIf, ifc.Index)
+			} else {
+				// The IPv4 index is in network byte order
+				index := make([]byte, 4)
+				binary.BigEndian.PutUint32(index, uint32(ifc.Index))
+				bindErr = windows.SetsockoptInt(windows.Handle(fd),
+					windows.IPPROTO_IP, ipUnicastIf,
+					int(binary.LittleEndian.Uint32(index)))
+			}
+		})
+		if err == nil {
+			err = bindErr
+		}
+
+		return
+	}
+}
@@ -1,5 +1,144 @@
 package types
 
+import (
+	"net/url"
+)
+
 type RemoteData struct {
 	Priority int `json:"priority"`
 }
+
+// ReconnectPolicy controls automatic reconnects, delays are in seconds
+// and zero values use the defaults. Jitter uses the default only when
+// unset, a jitter of zero disables it.
+type ReconnectPolicy struct {
+	MaxAttempts  int      `json:"max_attempts"`
+	InitialDelay int      `json:"initial_delay"`
+	MaxDelay     int      `json:"max_delay"`
+	Multiplier   float64  `json:"multiplier"`
+	Jitter       *float64 `json:"jitter"`
+	ResetAfter   int      `json:"reset_after"`
+	Retry        []string `json:"retry"`
+}
+
+func (r *ReconnectPolicy) Copy() *ReconnectPolicy {
+	if r == nil {
+		return nil
+	}
+
+	policy := *r
+	if r.Jitter != nil {
+		jitter := *r.Jitter
+		policy.Jitter = &jitter
+	}
+	if r.Retry != nil {
+		policy.Retry = append([]string{}, r.Retry...)
+	}
+
+	return &policy
+}
+
+// Proxy sets the proxy for outbound connections, the url scheme selects
+// the proxy type with http or socks5, https is only supported for service
+// requests and is rejected for OpenVPN connections. NoProxy contains hosts, domain
+// suffixes starting with a dot, addresses or networks that are connected
+// directly. UseEnv uses the HTTPS_PROXY and NO_PROXY environment variables
+// when the url is not set.
+type Proxy struct {
+	Url     string   `json:"url"`
+	NoProxy []string `json:"no_proxy"`
+	UseEnv  bool     `json:"use_env"`
+}
+
+func (p *Proxy) Copy() *Proxy {
+	if p == nil {
+		return nil
+	}
+
+	proxy := *p
+	if p.NoProxy != nil {
+		proxy.NoProxy = append([]string{}, p.NoProxy...)
+	}
+
+	return &proxy
+}
+
+// Redact returns a copy of the proxy with the url password redacted
+func (p *Proxy) Redact() *Proxy {
+	proxy := p.Copy()
+	if proxy == nil || proxy.Url == "" {
+		return proxy
+	}
+
+	u, err := url.Parse(proxy.Url)
+	if err != nil {
+		proxy.Url = ""
+		return proxy
+	}
+	proxy.Url = u.Redacted()
+
+	return proxy
+}
+
+// Unredact restores the url of the current proxy when the url is the
+// redacted url of the current proxy
+func (p *Proxy) Unredact(cur *Proxy) {
+	if p == nil || cur == nil || p.Url == "" {
+		return
+	}
+
+	if p.Url == cur.Redact().Url {
+		p.Url = cur.Url
+	}
+}
+
+// Schedule limits a system profile to connection windows. Days are day
+// names such as mon or monday, a window without days applies to every
+// day. Start and end are HH:MM in the timezone, a window with an end
+// before the start continues into the next day. Warning is the number of
+// seconds before a scheduled disconnect to send an event.
+type Schedule struct {
+	Timezone string           `json:"timezone"`
+	Windows  []ScheduleWindow `json:"windows"`
+	Warning  int              `json:"warning"`
+}
+
+type ScheduleWindow struct {
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+func (s *Schedule) Copy() *Schedule {
+	if s == nil {
+		return nil
+	}
+
+	sched := *s
+	if s.Windows != nil {
+		sched.Windows = []ScheduleWindow{}
+		for _, window := range s.Windows {
+			if window.Days != nil {
+				window.Days = append([]string{}, window.Days...)
+			}
+			sched.Windows = append(sched.Windows, window)
+		}
+	}
+
+	return &sched
+}
+
+// TrustedNetwork is a rule that detects a trusted network, every set field
+// must match. Host and HostPin are required, Host is a host:port that must
+// be reachable over TLS outside of the tunnel with a certificate matching
+// HostPin. GatewayMac matches the hardware address of the default gateway,
+// SearchDomain matches a system DNS search domain and Subnet matches a
+// local interface address. Interfaces, addresses and search domains of
+// active connections are ignored.
+type TrustedNetwork struct {
+	GatewayMac   string `json:"gateway_mac"`
+	SearchDomain string `json:"search_domain"`
+	Host         string `json:"host"`
+	HostPin      string `json:"host_pin"`
+	Subnet       string `json:"subnet"`
+}
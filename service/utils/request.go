@@ -19,7 +19,11 @@ import (
 )
 
 var (
+	// ProxyFunc is set by the proxy package which cannot be imported
+	// from utils
+	ProxyFunc       func(*http.Request) (*url.URL, error)
 	clientTransport = &http.Transport{
+		Proxy:               proxyFunc,
 		DisableKeepAlives:   true,
 		TLSHandshakeTimeout: 5 * time.Second,
 		TLSClientConfig: &tls.Config{
@@ -37,6 +41,13 @@ var (
 	}
 )
 
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	if ProxyFunc == nil {
+		return nil, nil
+	}
+	return ProxyFunc(req)
+}
+
 type ipResp struct {
 	Ip string `json:"ip"`
 }
@@ -0,0 +1,49 @@
+package watch
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/dns"
+	"github.com/sirupsen/logrus"
+)
+
+// dnsWatchLinux applies the DNS configuration of the connections again
+// when resolv.conf is modified or the resolved link is reset, resolved
+// does not modify resolv.conf and is checked periodically
+func dnsWatchLinux() {
+	events, err := dns.Watch()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("watch: Failed to watch resolv.conf, using polling")
+		events = nil
+	}
+
+	for {
+		select {
+		case <-events:
+			time.Sleep(500 * time.Millisecond)
+		case <-time.After(5 * time.Second):
+		}
+
+		restores, err := dns.Repair()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("watch: Failed to check DNS configuration")
+		}
+
+		for _, restore := range restores {
+			writer := restore.Writer
+			if writer == "" {
+				writer = "unknown"
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"iface":   restore.Iface,
+				"backend": restore.Backend,
+				"writer":  writer,
+			}).Warn("watch: DNS configuration removed, restored DNS")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package watch
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/config"
+	"github.com/pritunl/pritunl-client-electron/service/connection"
+	"github.com/pritunl/pritunl-client-electron/service/dns"
+	"github.com/pritunl/pritunl-client-electron/service/dnsstub"
+	"github.com/sirupsen/logrus"
+)
+
+func getDnsStubRoutes() (routes []*dnsstub.Route) {
+	routes = []*dnsstub.Route{}
+
+	for _, conn := range connection.GlobalStore.GetAll() {
+		if conn.Data.Status != connection.Connected ||
+			conn.Profile.DisableDns || len(conn.Data.DnsServers) == 0 {
+
+			continue
+		}
+
+		for _, domain := range conn.Data.SearchDomains {
+			routes = append(routes, &dnsstub.Route{
+				Domain:  domain,
+				Servers: conn.Data.DnsServers,
+			})
+		}
+
+		if conn.Profile.ForceDns {
+			routes = append(routes, &dnsstub.Route{
+				Servers: conn.Data.DnsServers,
+			})
+		}
+	}
+
+	return
+}
+
+// clearDnsStubGlobal removes the global DNS set for the stub by a
+// previous service
+func clearDnsStubGlobal() {
+	err := dns.ClearGlobal()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("watch: Failed to clear DNS stub global DNS")
+	}
+}
+
+// StopDnsStub stops the DNS stub and removes the global DNS of the stub
+func StopDnsStub() {
+	if !dnsstub.IsRunning() {
+		return
+	}
+
+	dnsstub.Stop()
+	clearDnsStubGlobal()
+}
+
+// dnsStubWatch runs the DNS stub resolver and updates the routes as
+// connections are added and removed, the upstream servers are only
+// captured when no connections are active
+func dnsStubWatch() {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			}).Error("watch: DNS stub watch panic")
+			time.Sleep(10 * time.Second)
+			go dnsStubWatch()
+		}
+	}()
+
+	addr := config.Config.DnsStub.Address
+	if addr == "" {
+		addr = dnsstub.DefaultAddress
+	}
+
+	ttl := dnsstub.DefaultCache
+	if config.Config.DnsStub.CacheTtl != 0 {
+		ttl = time.Duration(config.Config.DnsStub.CacheTtl) * time.Second
+	}
+
+	for {
+		err := dnsstub.Start(addr, ttl)
+		if err == nil {
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"address": addr,
+			"error":   err,
+		}).Error("watch: Failed to start DNS stub")
+		time.Sleep(30 * time.Second)
+	}
+
+	// Link scoped resolvers send queries through the tunnel interface
+	// where the loopback stub is unreachable, use the stub globally
+	if dns.IsLinkScoped() {
+		err := dns.SetGlobal([]string{dnsstub.GetHost()})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("watch: Failed to set DNS stub as global DNS")
+		}
+	} else {
+		clearDnsStubGlobal()
+	}
+
+	for {
+		if !connection.GlobalStore.IsActive() {
+			servers, err := dnsstub.GetSystemServers()
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+				}).Error("watch: Failed to get system DNS servers")
+			} else {
+				dnsstub.SetUpstream(servers)
+			}
+		}
+
+		dnsstub.Update(getDnsStubRoutes())
+
+		time.Sleep(1 * time.Second)
+	}
+}
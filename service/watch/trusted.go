@@ -0,0 +1,130 @@
+package watch
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/pritunl/pritunl-client-electron/service/connection"
+	"github.com/pritunl/pritunl-client-electron/service/event"
+	"github.com/pritunl/pritunl-client-electron/service/sprofile"
+	"github.com/pritunl/pritunl-client-electron/service/trusted"
+	"github.com/pritunl/pritunl-client-electron/service/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type TrustedEvent struct {
+	Id      string `json:"id"`
+	Trusted bool   `json:"trusted"`
+}
+
+func getTrustedExclude() (exclude *trusted.Exclude) {
+	exclude = &trusted.Exclude{
+		Ifaces:  []string{},
+		Addrs:   []string{},
+		Domains: []string{},
+	}
+
+	for _, conn := range connection.GlobalStore.GetAll() {
+		exclude.Ifaces = append(exclude.Ifaces,
+			conn.Data.Iface, conn.Data.WgTunIface)
+		exclude.Addrs = append(exclude.Addrs, conn.Data.ClientAddr)
+		exclude.Domains = append(exclude.Domains,
+			conn.Data.SearchDomains...)
+	}
+
+	return
+}
+
+// checkTrusted evaluates the trusted network rules of the system profiles
+// and activates or deactivates profiles when the trusted state changes
+func checkTrusted(state *trusted.State, sprfls []*sprofile.Sprofile) {
+	for _, sprfl := range sprfls {
+		isTrusted := state.IsTrusted(sprfl.TrustedNetworks)
+		if !trusted.SetTrusted(sprfl.Id, isTrusted) {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"profile_id": sprfl.Id,
+			"trusted":    isTrusted,
+		}).Info("watch: Trusted network state changed")
+
+		if isTrusted {
+			if sprfl.State {
+				sprofile.SetState(sprfl.Id, false)
+			}
+		} else if !sprfl.State {
+			sprofile.SetState(sprfl.Id, true)
+		}
+
+		evt := &event.Event{
+			Type: "trusted_network",
+			Data: &TrustedEvent{
+				Id:      sprfl.Id,
+				Trusted: isTrusted,
+			},
+		}
+		evt.Init()
+	}
+}
+
+func trustedWatch() {
+	defer func() {
+		panc := recover()
+		if panc != nil {
+			logrus.WithFields(logrus.Fields{
+				"trace": string(debug.Stack()),
+				"panic": panc,
+			}).Error("watch: Trusted network watch panic")
+			time.Sleep(10 * time.Second)
+			go trustedWatch()
+		}
+	}()
+
+	lastHash := ""
+	lastCheck := time.Time{}
+
+	for {
+		time.Sleep(3 * time.Second)
+
+		if connection.Shutdown {
+			return
+		}
+
+		allSprfls, err := sprofile.GetAll()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("watch: Failed to get system profiles")
+			continue
+		}
+
+		sprfls := []*sprofile.Sprofile{}
+		for _, sprfl := range allSprfls {
+			if len(sprfl.TrustedNetworks) > 0 {
+				sprfls = append(sprfls, sprfl)
+			}
+		}
+
+		if len(sprfls) == 0 {
+			lastHash = ""
+			continue
+		}
+
+		state := trusted.GetState(getTrustedExclude())
+		hash := state.Hash()
+
+		if hash == lastHash && utils.SinceAbs(lastCheck) < 60*time.Second {
+			continue
+		}
+
+		if hash != lastHash && lastHash != "" {
+			logrus.Info("watch: Network changed, checking trusted networks")
+		}
+
+		lastHash = hash
+		lastCheck = time.Now()
+
+		checkTrusted(state, sprfls)
+	}
+}
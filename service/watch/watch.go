@@ -12,6 +12,7 @@ import (
 	"github.com/pritunl/pritunl-client-electron/service/autoclean"
 	"github.com/pritunl/pritunl-client-electron/service/config"
 	"github.com/pritunl/pritunl-client-electron/service/connection"
+	"github.com/pritunl/pritunl-client-electron/service/dnsstub"
 	"github.com/pritunl/pritunl-client-electron/service/event"
 	"github.com/pritunl/pritunl-client-electron/service/utils"
 	"github.com/sirupsen/logrus"
@@ -127,7 +128,7 @@ func wakeWatch() {
 
 				logrus.Warn("watch: Wakeup restarting...")
 
-				connection.RestartProfiles(false)
+				connection.WakeProfiles()
 			} else {
 				restartLock.Unlock()
 			}
@@ -149,6 +150,11 @@ func dnsWatch() {
 		}
 	}()
 
+	if runtime.GOOS == "linux" {
+		dnsWatchLinux()
+		return
+	}
+
 	if runtime.GOOS != "darwin" {
 		return
 	}
@@ -308,9 +314,19 @@ func StartWatch() {
 	} else {
 		go wakeWatch()
 	}
+	go trustedWatch()
 	if config.Config.DisableDnsWatch {
 		logrus.Info("watch: DNS watch disabled")
 	} else {
 		go dnsWatch()
 	}
+	if config.Config.DnsStub != nil && config.Config.DnsStub.Enabled {
+		if dnsstub.Supported {
+			go dnsStubWatch()
+		} else {
+			logrus.Warn("watch: DNS stub not supported on platform")
+		}
+	} else {
+		clearDnsStubGlobal()
+	}
 }